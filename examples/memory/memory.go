@@ -49,6 +49,19 @@ func main() {
 		json.NewEncoder(w).Encode(response)
 	})
 
+	mux.HandleFunc("/quota", func(w http.ResponseWriter, r *http.Request) {
+		status := limiter.Status(middleware.DefaultClientIDExtractor(r))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]any{
+			"limit":     status.Limit,
+			"remaining": status.Remaining,
+			"reset_at":  status.ResetAt,
+			"blocked":   status.Blocked,
+		})
+	})
+
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
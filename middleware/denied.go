@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+)
+
+// DeniedHandler writes the response for a request rejected by the limiter. The
+// middleware has already set the Retry-After and rate limit headers.
+type DeniedHandler func(w http.ResponseWriter, r *http.Request, result *ratelimiter.Result)
+
+// WithDeniedHandler replaces the 429 response written for denied requests.
+// When set, WithIncludeJSON has no effect.
+func WithDeniedHandler(handler DeniedHandler) MiddlewareOption {
+	return func(m *RateLimiterMiddleware) {
+		m.deniedHandler = handler
+	}
+}
+
+// deniedTypes are the media types NegotiatedDeniedHandler can render, in order
+// of preference when the client accepts several equally.
+var deniedTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"text/plain",
+	"text/html",
+}
+
+// NegotiatedDeniedHandler is a DeniedHandler that renders the denial as JSON,
+// an RFC 9457 problem detail, plain text or HTML, whichever the Accept header
+// prefers. Requests without a usable Accept header get JSON.
+func NegotiatedDeniedHandler(w http.ResponseWriter, r *http.Request, result *ratelimiter.Result) {
+	contentType := negotiate(r.Header.Values("Accept"), deniedTypes)
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	switch contentType {
+	case "application/problem+json":
+		writeProblem(w, result)
+	case "text/plain":
+		fmt.Fprintf(w, "%s\nRetry after %d seconds.\n", result.ErrorMessage, result.RetryAfterSec)
+	case "text/html":
+		deniedPage.Execute(w, result)
+	default:
+		w.Write([]byte(result.FormatJSON()))
+	}
+}
+
+// writeProblem writes the denial as an RFC 9457 problem detail. The limit,
+// retry time and Extra fields of the result become extension members; Extra
+// fields named like a standard member, or whose value cannot be encoded, are
+// left out.
+func writeProblem(w http.ResponseWriter, result *ratelimiter.Result) {
+	members := make(map[string]any, len(result.Extra)+6)
+	for name, value := range result.Extra {
+		if _, err := json.Marshal(value); err == nil {
+			members[name] = value
+		}
+	}
+
+	members["type"] = "about:blank"
+	members["title"] = http.StatusText(http.StatusTooManyRequests)
+	members["status"] = http.StatusTooManyRequests
+	members["limit"] = result.Limit
+	members["retry_after"] = result.RetryAfter
+	members["retry_after_seconds"] = result.RetryAfterSec
+	delete(members, "detail")
+	if result.ErrorMessage != "" {
+		members["detail"] = result.ErrorMessage
+	}
+
+	json.NewEncoder(w).Encode(members)
+}
+
+var deniedPage = template.Must(template.New("denied").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Too Many Requests</title></head>
+<body>
+<h1>Too Many Requests</h1>
+<p>{{.ErrorMessage}}</p>
+<p>Please try again in {{.RetryAfterSec}} seconds.</p>
+</body>
+</html>
+`))
+
+// negotiate returns the offer with the highest quality in the Accept header
+// values, or the first offer if none is acceptable.
+func negotiate(accept []string, offers []string) string {
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		if q := quality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// quality returns the q-value the Accept header values give to mediaType,
+// taken from the most specific matching range. An absent header accepts
+// everything.
+func quality(accept []string, mediaType string) float64 {
+	if len(accept) == 0 {
+		return 1
+	}
+
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			params := strings.Split(mediaRange, ";")
+			rangeType := strings.ToLower(strings.TrimSpace(params[0]))
+
+			var s int
+			switch rangeType {
+			case mediaType:
+				s = 2
+			case typ + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			default:
+				continue
+			}
+			if s < specificity {
+				continue
+			}
+
+			rangeQ := 1.0
+			for _, param := range params[1:] {
+				name, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "q") {
+					if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+						rangeQ = parsed
+					}
+				}
+			}
+			q, specificity = rangeQ, s
+		}
+	}
+	return q
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+)
+
+func deniedRecorder(t *testing.T, handler http.Handler, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rec.Code)
+	}
+	return rec
+}
+
+func TestWithDeniedHandler(t *testing.T) {
+	var got *ratelimiter.Result
+	middleware := NewRateLimiterMiddleware(
+		ratelimiter.New(ratelimiter.WithMaxRequests(1)),
+		WithDeniedHandler(func(w http.ResponseWriter, r *http.Request, result *ratelimiter.Result) {
+			got = result
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("slow down"))
+		}),
+	)
+
+	rec := deniedRecorder(t, middleware.Handler(okHandler()), "")
+
+	if got == nil || got.Allowed {
+		t.Fatalf("Expected handler to receive the denied result, got %+v", got)
+	}
+	if rec.Body.String() != "slow down" {
+		t.Errorf("Expected custom body, got %q", rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After to be set before the handler runs")
+	}
+}
+
+func TestNegotiatedDeniedHandler(t *testing.T) {
+	middleware := NewRateLimiterMiddleware(
+		ratelimiter.New(ratelimiter.WithMaxRequests(1), ratelimiter.WithErrorMessage(`<b>"Slow" down</b>`)),
+		WithDeniedHandler(NegotiatedDeniedHandler),
+	)
+	handler := middleware.Handler(okHandler())
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/json", `"limit":1`},
+		{"*/*", "application/json", `"limit":1`},
+		{"application/json", "application/json", `"limit":1`},
+		{"application/problem+json", "application/problem+json", `"status":429`},
+		{"text/plain", "text/plain", "Retry after"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html", "&lt;b&gt;&#34;Slow&#34; down&lt;/b&gt;"},
+		{"text/*;q=0.5, application/json;q=0.4", "text/plain", "Retry after"},
+		{"text/*, text/plain;q=0", "text/html", "<h1>"},
+		{"image/png", "application/json", `"limit":1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			rec := deniedRecorder(t, handler, tt.accept)
+
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType+";") {
+				t.Errorf("Expected Content-Type %s, got %s", tt.contentType, got)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("Expected body to contain %s, got %s", tt.body, rec.Body.String())
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Error("Expected Vary: Accept")
+			}
+		})
+	}
+}
+
+func TestNegotiatedDeniedHandler_Problem(t *testing.T) {
+	middleware := NewRateLimiterMiddleware(
+		ratelimiter.New(
+			ratelimiter.WithMaxRequests(1),
+			ratelimiter.WithErrorMessage("Too fast"),
+			ratelimiter.WithJSONFields(map[string]any{"docs": "https://example.com/limits", "title": "Overridden"}),
+		),
+		WithDeniedHandler(NegotiatedDeniedHandler),
+	)
+
+	rec := deniedRecorder(t, middleware.Handler(okHandler()), "application/problem+json")
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	for field, want := range map[string]any{"type": "about:blank", "title": "Too Many Requests", "status": 429.0, "detail": "Too fast", "docs": "https://example.com/limits"} {
+		if body[field] != want {
+			t.Errorf("Expected %s %v, got %v", field, want, body[field])
+		}
+	}
+	if _, ok := body["retry_after"]; !ok {
+		t.Error("Expected retry_after extension member")
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+)
+
+type HeaderFormat int
+
+const (
+	// HeaderIETF emits the RateLimit and RateLimit-Policy structured fields from
+	// the IETF httpapi rate limit headers draft.
+	HeaderIETF HeaderFormat = 1 << iota
+	// HeaderLegacy emits X-RateLimit-Limit, X-RateLimit-Remaining and
+	// X-RateLimit-Reset (Unix seconds).
+	HeaderLegacy
+)
+
+const policyName = "default"
+
+func (m *RateLimiterMiddleware) setRateLimitHeaders(w http.ResponseWriter, policy string, limiter *ratelimiter.RateLimiter, result *ratelimiter.Result) {
+	if m.headerFormat == 0 {
+		return
+	}
+
+	resetAt := result.ResetAt
+	if !result.Allowed {
+		resetAt = result.RetryAfter
+	}
+	resetSec := int(math.Ceil(resetAt.Sub(limiter.Clock().Now()).Seconds()))
+	if resetSec < 0 {
+		resetSec = 0
+	}
+
+	h := w.Header()
+
+	if m.headerFormat&HeaderIETF != 0 {
+		h.Set("RateLimit-Policy", fmt.Sprintf("%q;q=%d;w=%d", policy, result.Limit, int(limiter.WindowDuration().Seconds())))
+		h.Set("RateLimit", fmt.Sprintf("%q;r=%d;t=%d", policy, result.Remaining, resetSec))
+	}
+
+	if m.headerFormat&HeaderLegacy != 0 {
+		h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+	}
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter/ratelimitertest"
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func TestHandler_NoRateLimitHeadersByDefault(t *testing.T) {
+	limiter := ratelimiter.New(ratelimiter.WithMaxRequests(10))
+	middleware := NewRateLimiterMiddleware(limiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	for _, name := range []string{"RateLimit", "RateLimit-Policy", "X-RateLimit-Limit"} {
+		if rec.Header().Get(name) != "" {
+			t.Errorf("Expected %s header not to be set", name)
+		}
+	}
+}
+
+func TestHandler_IETFRateLimitHeaders(t *testing.T) {
+	limiter := ratelimiter.New(
+		ratelimiter.WithMaxRequests(2),
+		ratelimiter.WithWindowDuration(time.Minute),
+	)
+	middleware := NewRateLimiterMiddleware(limiter, WithRateLimitHeaders(HeaderIETF))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("RateLimit-Policy"); got != `"default";q=2;w=60` {
+		t.Errorf("Unexpected RateLimit-Policy header: %s", got)
+	}
+	if got := rec.Header().Get("RateLimit"); got != `"default";r=1;t=60` {
+		t.Errorf("Unexpected RateLimit header: %s", got)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Error("Expected legacy headers not to be set")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit"); got != `"default";r=0;t=60` {
+		t.Errorf("Unexpected RateLimit header on denied response: %s", got)
+	}
+}
+
+func TestHandler_LegacyRateLimitHeaders(t *testing.T) {
+	limiter := ratelimiter.New(
+		ratelimiter.WithMaxRequests(5),
+		ratelimiter.WithWindowDuration(time.Minute),
+	)
+	middleware := NewRateLimiterMiddleware(limiter, WithRateLimitHeaders(HeaderIETF|HeaderLegacy))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "5" {
+		t.Errorf("Expected X-RateLimit-Limit 5, got %s", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "4" {
+		t.Errorf("Expected X-RateLimit-Remaining 4, got %s", got)
+	}
+
+	reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		t.Fatalf("Expected X-RateLimit-Reset to be a Unix timestamp: %v", err)
+	}
+	if diff := time.Unix(reset, 0).Sub(start.Add(time.Minute)).Abs(); diff > 2*time.Second {
+		t.Errorf("Expected X-RateLimit-Reset at the end of the window, off by %v", diff)
+	}
+	if rec.Header().Get("RateLimit") == "" {
+		t.Error("Expected IETF headers to be set as well")
+	}
+}
+
+func TestHandler_RateLimitHeadersUseLimiterClock(t *testing.T) {
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	limiter := ratelimiter.New(
+		ratelimiter.WithMaxRequests(2),
+		ratelimiter.WithWindowDuration(time.Minute),
+		ratelimiter.WithClock(clock),
+	)
+	middleware := NewRateLimiterMiddleware(limiter, WithRateLimitHeaders(HeaderIETF))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	clock.Advance(45 * time.Second)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("RateLimit"); got != `"default";r=0;t=15` {
+		t.Errorf("Unexpected RateLimit header: %s", got)
+	}
+}
+
+// unavailableStorage fails every fixed window check.
+type unavailableStorage struct {
+	ratelimiter.StorageV2
+}
+
+func (unavailableStorage) CheckAndIncrementN(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*storage.ClientData, bool, error) {
+	return nil, false, errors.New("storage unavailable")
+}
+
+func TestHandler_RateLimitHeadersOnDegradedResult(t *testing.T) {
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	limiter := ratelimiter.New(
+		ratelimiter.WithStorageV2(unavailableStorage{}),
+		ratelimiter.WithMaxRequests(5),
+		ratelimiter.WithClock(clock),
+		ratelimiter.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	middleware := NewRateLimiterMiddleware(limiter, WithRateLimitHeaders(HeaderIETF|HeaderLegacy))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected fail-open to allow the request, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit"); got != `"default";r=5;t=0` {
+		t.Errorf("Unexpected RateLimit header: %s", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "5" {
+		t.Errorf("Expected X-RateLimit-Remaining 5, got %s", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Reset"); got != strconv.FormatInt(clock.Now().Unix(), 10) {
+		t.Errorf("Expected X-RateLimit-Reset at the current time, got %s", got)
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
 )
 
 type ClientIDExtractor func(*http.Request) string
 
+type CostFunc func(*http.Request) int
+
 func DefaultClientIDExtractor(r *http.Request) string {
 	forwarded := r.Header.Get("X-Forwarded-For")
 	if forwarded != "" {
@@ -35,7 +38,12 @@ func DefaultClientIDExtractor(r *http.Request) string {
 type RateLimiterMiddleware struct {
 	limiter           *ratelimiter.RateLimiter
 	clientIDExtractor ClientIDExtractor
+	costFunc          CostFunc
 	includeJSON       bool
+	headerFormat      HeaderFormat
+	routes            *http.ServeMux
+	policies          map[string]*ratelimiter.RateLimiter
+	deniedHandler     DeniedHandler
 }
 
 type MiddlewareOption func(*RateLimiterMiddleware)
@@ -46,12 +54,29 @@ func WithClientIDExtractor(extractor ClientIDExtractor) MiddlewareOption {
 	}
 }
 
+// WithCostFunc charges each request the cost returned by fn instead of 1. Costs
+// below 1 are charged as 1, so a cost computed from client input can never give
+// quota back.
+func WithCostFunc(fn CostFunc) MiddlewareOption {
+	return func(m *RateLimiterMiddleware) {
+		m.costFunc = fn
+	}
+}
+
 func WithIncludeJSON(include bool) MiddlewareOption {
 	return func(m *RateLimiterMiddleware) {
 		m.includeJSON = include
 	}
 }
 
+// WithRateLimitHeaders emits rate limit headers in the given formats on both
+// allowed and denied responses. Formats can be combined: HeaderIETF|HeaderLegacy.
+func WithRateLimitHeaders(format HeaderFormat) MiddlewareOption {
+	return func(m *RateLimiterMiddleware) {
+		m.headerFormat = format
+	}
+}
+
 func NewRateLimiterMiddleware(limiter *ratelimiter.RateLimiter, opts ...MiddlewareOption) *RateLimiterMiddleware {
 	m := &RateLimiterMiddleware{
 		limiter:           limiter,
@@ -68,46 +93,73 @@ func NewRateLimiterMiddleware(limiter *ratelimiter.RateLimiter, opts ...Middlewa
 
 func (m *RateLimiterMiddleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientID := m.clientIDExtractor(r)
-
-		result := m.limiter.Allow(clientID)
-
-		if !result.Allowed {
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", result.RetryAfterSec))
-
-			if m.includeJSON {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-				w.Write([]byte(result.FormatJSON()))
-			} else {
-				w.WriteHeader(http.StatusTooManyRequests)
-			}
-			return
-		}
-
-		next.ServeHTTP(w, r)
+		m.serve(w, r, next)
 	})
 }
 
 func (m *RateLimiterMiddleware) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		clientID := m.clientIDExtractor(r)
+		m.serve(w, r, next)
+	}
+}
 
-		result := m.limiter.Allow(clientID)
+func (m *RateLimiterMiddleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	policy, limiter := m.policyFor(r)
 
-		if !result.Allowed {
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", result.RetryAfterSec))
+	clientID := m.clientIDExtractor(r)
+	if m.routes != nil {
+		clientID = policy + "|" + clientID
+	}
 
-			if m.includeJSON {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-				w.Write([]byte(result.FormatJSON()))
-			} else {
-				w.WriteHeader(http.StatusTooManyRequests)
-			}
-			return
-		}
+	cost := 1
+	if m.costFunc != nil {
+		cost = max(1, m.costFunc(r))
+	}
+
+	reservation, _ := limiter.ReserveNContext(r.Context(), clientID, cost)
+	result := reservation.Result()
+
+	m.setRateLimitHeaders(w, policy, limiter, result)
+
+	if !result.Allowed {
+		m.deny(w, r, result)
+		return
+	}
+
+	if result.Delay > 0 && !wait(r, limiter.Clock(), result.Delay) {
+		reservation.Cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+func (m *RateLimiterMiddleware) deny(w http.ResponseWriter, r *http.Request, result *ratelimiter.Result) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", result.RetryAfterSec))
+
+	if m.deniedHandler != nil {
+		m.deniedHandler(w, r, result)
+		return
+	}
+
+	if m.includeJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(result.FormatJSON()))
+	} else {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+}
 
-		next(w, r)
+// wait holds the request for the delay assigned by a queueing algorithm. It
+// returns false if the request context ends first, in which case the caller
+// gives the reserved units back.
+func wait(r *http.Request, clock ratelimiter.Clock, delay time.Duration) bool {
+	select {
+	case <-clock.After(delay):
+		return true
+	case <-r.Context().Done():
+		return false
 	}
 }
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -313,3 +315,183 @@ func TestHandler_CustomClientIDExtractor(t *testing.T) {
 		t.Error("First request with key2 should be allowed")
 	}
 }
+
+func TestHandler_LeakyBucketDelaysRequest(t *testing.T) {
+	limiter := ratelimiter.New(
+		ratelimiter.WithAlgorithm(ratelimiter.LeakyBucket),
+		ratelimiter.WithRefillRate(20),
+		ratelimiter.WithQueueDepth(2),
+	)
+	middleware := NewRateLimiterMiddleware(limiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Request %d should be allowed, got %d", i+1, rec.Code)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected second request to be delayed, took %v", elapsed)
+	}
+}
+
+func TestHandler_LeakyBucketRejectsWhenQueueFull(t *testing.T) {
+	limiter := ratelimiter.New(
+		ratelimiter.WithAlgorithm(ratelimiter.LeakyBucket),
+		ratelimiter.WithRefillRate(1),
+		ratelimiter.WithQueueDepth(1),
+	)
+	middleware := NewRateLimiterMiddleware(limiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rec.Code)
+	}
+}
+
+func TestHandler_LeakyBucketHonorsContext(t *testing.T) {
+	limiter := ratelimiter.New(
+		ratelimiter.WithAlgorithm(ratelimiter.LeakyBucket),
+		ratelimiter.WithRefillRate(0.1),
+		ratelimiter.WithQueueDepth(2),
+	)
+	middleware := NewRateLimiterMiddleware(limiter)
+
+	called := 0
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	if called != 1 {
+		t.Errorf("Expected queued request to be abandoned, handler called %d times", called)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestHandler_LeakyBucketReleasesAbandonedRequests(t *testing.T) {
+	limiter := ratelimiter.New(
+		ratelimiter.WithAlgorithm(ratelimiter.LeakyBucket),
+		ratelimiter.WithRefillRate(0.1),
+		ratelimiter.WithQueueDepth(3),
+	)
+	middleware := NewRateLimiterMiddleware(limiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(req.Context(), 10*time.Millisecond)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		cancel()
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Request %d: expected status 503, got %d", i+1, rec.Code)
+		}
+	}
+
+	if status := limiter.Status("192.168.1.1"); status.Remaining != 2 {
+		t.Errorf("Expected abandoned requests to give their slots back, got Remaining %d", status.Remaining)
+	}
+}
+
+func TestHandler_CostFunc(t *testing.T) {
+	limiter := ratelimiter.New(ratelimiter.WithMaxRequests(10))
+
+	costFunc := func(r *http.Request) int {
+		cost, err := strconv.Atoi(r.URL.Query().Get("items"))
+		if err != nil || cost < 1 {
+			return 1
+		}
+		return cost
+	}
+
+	middleware := NewRateLimiterMiddleware(limiter, WithCostFunc(costFunc))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/bulk?items=6", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("First batch should be allowed, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Second batch should exceed the limit, got %d", rec.Code)
+	}
+}
+
+func TestHandler_CostFuncBelowOne(t *testing.T) {
+	limiter := ratelimiter.New(ratelimiter.WithMaxRequests(2))
+
+	middleware := NewRateLimiterMiddleware(limiter, WithCostFunc(func(r *http.Request) int {
+		cost, _ := strconv.Atoi(r.URL.Query().Get("items"))
+		return cost
+	}))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i, items := range []string{"2", "-10", "0"} {
+		req := httptest.NewRequest("POST", "/bulk?items="+items, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		want := http.StatusOK
+		if i > 0 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Errorf("Request with %s items: expected status %d, got %d", items, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ProxyHeader names the header in which trusted proxies record client addresses.
+type ProxyHeader int
+
+const (
+	// ProxyHeaderXForwardedFor reads the X-Forwarded-For chain, as written by
+	// nginx, HAProxy and most cloud load balancers.
+	ProxyHeaderXForwardedFor ProxyHeader = iota
+	// ProxyHeaderForwarded reads the for= parameters of the RFC 7239 Forwarded
+	// header.
+	ProxyHeaderForwarded
+)
+
+type ipExtractor struct {
+	trustedProxies []netip.Prefix
+	proxyHeader    ProxyHeader
+	ipv4Bits       int
+	ipv6Bits       int
+}
+
+type IPExtractorOption func(*ipExtractor)
+
+// WithTrustedProxies lists the networks of the reverse proxies in front of the
+// server. Forwarding headers are only believed when they were added by one of
+// these proxies.
+func WithTrustedProxies(prefixes ...netip.Prefix) IPExtractorOption {
+	return func(e *ipExtractor) {
+		e.trustedProxies = append(e.trustedProxies, prefixes...)
+	}
+}
+
+// WithProxyHeader sets the header the trusted proxies write. Only that header
+// is read: a proxy usually passes the other one through unchanged from the
+// client. The default is ProxyHeaderXForwardedFor.
+func WithProxyHeader(header ProxyHeader) IPExtractorOption {
+	return func(e *ipExtractor) {
+		e.proxyHeader = header
+	}
+}
+
+// WithIPv4Prefix groups IPv4 clients by network prefix of the given length, so
+// that every address in the network shares one quota. The default is 32.
+func WithIPv4Prefix(bits int) IPExtractorOption {
+	if bits < 0 || bits > 32 {
+		panic(fmt.Sprintf("middleware: invalid IPv4 prefix length %d", bits))
+	}
+	return func(e *ipExtractor) {
+		e.ipv4Bits = bits
+	}
+}
+
+// WithIPv6Prefix groups IPv6 clients by network prefix of the given length. The
+// default is 64, the smallest network usually assigned to a single subscriber.
+func WithIPv6Prefix(bits int) IPExtractorOption {
+	if bits < 0 || bits > 128 {
+		panic(fmt.Sprintf("middleware: invalid IPv6 prefix length %d", bits))
+	}
+	return func(e *ipExtractor) {
+		e.ipv6Bits = bits
+	}
+}
+
+// NewIPExtractor returns a ClientIDExtractor that identifies clients by IP
+// address without trusting headers the client could forge.
+//
+// If the connection comes from a trusted proxy, the proxy chain in the header
+// chosen with WithProxyHeader is walked right to left and the first address not
+// belonging to a trusted proxy is the client. If an entry is not an IP address (such as "unknown" or an obfuscated
+// identifier), the proxy that added it is used. Otherwise, the connection's
+// remote address is the client.
+//
+// IPv4-mapped IPv6 addresses are treated as IPv4. Clients are keyed by address
+// ("192.0.2.1") when the prefix covers the whole address and by network
+// ("2001:db8:1:2::/64") otherwise.
+func NewIPExtractor(opts ...IPExtractorOption) ClientIDExtractor {
+	e := &ipExtractor{
+		ipv4Bits: 32,
+		ipv6Bits: 64,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e.extract
+}
+
+func (e *ipExtractor) extract(r *http.Request) string {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	client := peer
+	if e.trusted(peer) {
+		client = e.walk(peer, e.chain(r))
+	}
+	return e.key(client)
+}
+
+// key returns the canonical client key for addr.
+func (e *ipExtractor) key(addr netip.Addr) string {
+	addr = addr.Unmap()
+
+	bits := e.ipv6Bits
+	if addr.Is4() {
+		bits = e.ipv4Bits
+	}
+	if bits == addr.BitLen() {
+		return addr.String()
+	}
+
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
+
+// walk returns the client address from a proxy chain whose last hop was the
+// trusted proxy peer.
+func (e *ipExtractor) walk(peer netip.Addr, chain []string) netip.Addr {
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseIP(chain[i])
+		if !ok {
+			return client
+		}
+		client = addr
+		if !e.trusted(addr) {
+			return client
+		}
+	}
+	return client
+}
+
+func (e *ipExtractor) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range e.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// chain returns the addresses recorded by proxies in the configured header,
+// client first.
+func (e *ipExtractor) chain(r *http.Request) []string {
+	var chain []string
+	if e.proxyHeader == ProxyHeaderForwarded {
+		for _, value := range r.Header.Values("Forwarded") {
+			for _, element := range splitQuoted(value, ',') {
+				chain = append(chain, forwardedFor(element))
+			}
+		}
+		return chain
+	}
+
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(hop))
+		}
+	}
+	return chain
+}
+
+// forwardedFor returns the node of the for= parameter in one Forwarded element,
+// or "" if there is none.
+func forwardedFor(element string) string {
+	for _, pair := range splitQuoted(element, ';') {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = strings.ReplaceAll(value[1:len(value)-1], `\`, "")
+		}
+		return value
+	}
+	return ""
+}
+
+// splitQuoted splits s at sep, ignoring separators inside quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped := false, false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\' && quoted:
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseIP parses an address with an optional port, as found in RemoteAddr,
+// X-Forwarded-For and Forwarded ("192.0.2.1", "192.0.2.1:80", "[2001:db8::1]:80").
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.WithZone(""), true
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func trustedExtractor(opts ...IPExtractorOption) ClientIDExtractor {
+	return NewIPExtractor(append([]IPExtractorOption{WithTrustedProxies(
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8:ffff::/48"),
+	)}, opts...)...)
+}
+
+func TestIPExtractor(t *testing.T) {
+	tests := []struct {
+		name        string
+		remoteAddr  string
+		proxyHeader ProxyHeader
+		headers     map[string]string
+		want        string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			remoteAddr: "203.0.113.7:4000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.0.0.1:4000",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "single trusted proxy",
+			remoteAddr: "10.0.0.1:4000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.9"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "spoofed entries left of the client are ignored",
+			remoteAddr: "10.0.0.1:4000",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 198.51.100.9, 10.0.0.2"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "all hops trusted",
+			remoteAddr: "10.0.0.1:4000",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "invalid entry falls back to the proxy that added it",
+			remoteAddr: "10.0.0.1:4000",
+			headers:    map[string]string{"X-Forwarded-For": "unknown, 10.0.0.2"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:        "forwarded header",
+			remoteAddr:  "10.0.0.1:4000",
+			proxyHeader: ProxyHeaderForwarded,
+			headers:     map[string]string{"Forwarded": `for=192.0.2.60;proto=http;by=203.0.113.43, for="10.0.0.2:8080"`},
+			want:        "192.0.2.60",
+		},
+		{
+			name:        "forwarded IPv6 with port",
+			remoteAddr:  "[2001:db8:ffff::1]:443",
+			proxyHeader: ProxyHeaderForwarded,
+			headers:     map[string]string{"Forwarded": `For="[2001:db8:cafe::17]:4711"`},
+			want:        "2001:db8:cafe::/64",
+		},
+		{
+			name:       "forged Forwarded ignored when proxies write X-Forwarded-For",
+			remoteAddr: "10.0.0.1:4000",
+			headers: map[string]string{
+				"Forwarded":       "for=6.6.6.6",
+				"X-Forwarded-For": "203.0.113.7",
+			},
+			want: "203.0.113.7",
+		},
+		{
+			name:        "forged X-Forwarded-For ignored when proxies write Forwarded",
+			remoteAddr:  "10.0.0.1:4000",
+			proxyHeader: ProxyHeaderForwarded,
+			headers: map[string]string{
+				"Forwarded":       "for=192.0.2.60",
+				"X-Forwarded-For": "6.6.6.6",
+			},
+			want: "192.0.2.60",
+		},
+		{
+			name:        "forwarded obfuscated node",
+			remoteAddr:  "10.0.0.1:4000",
+			proxyHeader: ProxyHeaderForwarded,
+			headers:     map[string]string{"Forwarded": "for=_hidden, for=10.0.0.2"},
+			want:        "10.0.0.2",
+		},
+		{
+			name:       "IPv4-mapped trusted peer",
+			remoteAddr: "[::ffff:10.0.0.1]:4000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.9"},
+			want:       "198.51.100.9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extract := trustedExtractor(WithProxyHeader(tt.proxyHeader))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			if got := extract(req); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIPExtractor_MultipleHeaderLines(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Add("X-Forwarded-For", "1.1.1.1, 198.51.100.9")
+	req.Header.Add("X-Forwarded-For", "10.0.0.2")
+
+	if got := trustedExtractor()(req); got != "198.51.100.9" {
+		t.Errorf("Expected 198.51.100.9, got %s", got)
+	}
+}
+
+func TestIPExtractor_NoTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if got := NewIPExtractor()(req); got != "10.0.0.1" {
+		t.Errorf("Expected RemoteAddr when no proxy is trusted, got %s", got)
+	}
+}
+
+func TestIPExtractor_PrefixAggregation(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []IPExtractorOption
+		remoteAddr string
+		want       string
+	}{
+		{"IPv4 default", nil, "192.0.2.1:80", "192.0.2.1"},
+		{"IPv6 default /64", nil, "[2001:db8:1:2:aaaa::1]:80", "2001:db8:1:2::/64"},
+		{"IPv6 rotated address shares bucket", nil, "[2001:db8:1:2:bbbb::9]:80", "2001:db8:1:2::/64"},
+		{"IPv4-mapped is canonicalized", nil, "[::ffff:192.0.2.1]:80", "192.0.2.1"},
+		{"IPv6 zone is dropped", nil, "[fe80::1%eth0]:80", "fe80::/64"},
+		{"IPv4 custom prefix", []IPExtractorOption{WithIPv4Prefix(24)}, "192.0.2.77:80", "192.0.2.0/24"},
+		{"IPv4-mapped custom prefix", []IPExtractorOption{WithIPv4Prefix(24)}, "[::ffff:192.0.2.77]:80", "192.0.2.0/24"},
+		{"IPv6 custom prefix", []IPExtractorOption{WithIPv6Prefix(48)}, "[2001:db8:1:2::1]:80", "2001:db8:1::/48"},
+		{"IPv6 full address", []IPExtractorOption{WithIPv6Prefix(128)}, "[2001:db8:1:2::1]:80", "2001:db8:1:2::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+
+			if got := NewIPExtractor(tt.opts...)(req); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIPExtractor_InvalidPrefixPanics(t *testing.T) {
+	for _, opt := range []func(){
+		func() { WithIPv4Prefix(33) },
+		func() { WithIPv6Prefix(-1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected invalid prefix length to panic")
+				}
+			}()
+			opt()
+		}()
+	}
+}
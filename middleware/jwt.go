@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type jwtKey struct {
+	id  string
+	key any
+}
+
+type jwtExtractor struct {
+	keys     []jwtKey
+	claim    string
+	fallback KeyDimension
+}
+
+type JWTOption func(*jwtExtractor)
+
+// WithHMACKey accepts tokens signed with HS256, HS384 or HS512 using secret.
+// If id is not empty, it must match the token's "kid" header.
+func WithHMACKey(id string, secret []byte) JWTOption {
+	return func(e *jwtExtractor) {
+		e.keys = append(e.keys, jwtKey{id: id, key: secret})
+	}
+}
+
+// WithRSAKey accepts tokens signed with RS256-512 or PS256-512 by the holder of
+// key. If id is not empty, it must match the token's "kid" header.
+func WithRSAKey(id string, key *rsa.PublicKey) JWTOption {
+	return func(e *jwtExtractor) {
+		e.keys = append(e.keys, jwtKey{id: id, key: key})
+	}
+}
+
+// WithECDSAKey accepts tokens signed with ES256, ES384 or ES512 by the holder
+// of key. If id is not empty, it must match the token's "kid" header.
+func WithECDSAKey(id string, key *ecdsa.PublicKey) JWTOption {
+	return func(e *jwtExtractor) {
+		e.keys = append(e.keys, jwtKey{id: id, key: key})
+	}
+}
+
+// WithJWTClaim sets the claim that identifies the client. The default is "sub".
+func WithJWTClaim(name string) JWTOption {
+	return func(e *jwtExtractor) {
+		e.claim = name
+	}
+}
+
+// WithJWTFallback sets the dimension identifying requests without a valid
+// token. The default is IPDimension(NewIPExtractor()), the connection's remote
+// address; pass an extractor with trusted proxies when behind a proxy.
+func WithJWTFallback(dim KeyDimension) JWTOption {
+	return func(e *jwtExtractor) {
+		e.fallback = dim
+	}
+}
+
+// NewJWTExtractor returns a ClientIDExtractor that identifies clients by a
+// claim of the bearer JWT in the Authorization header. Keys have the form
+// "sub=alice", and fallback keys are named after their dimension, such as
+// "ip=192.0.2.1", with both names and values escaped, so the two never collide.
+//
+// The token must be signed by one of the configured keys and must not be
+// expired or used before its "nbf" time. Requests without a token, with an
+// invalid token or without the claim are identified by the fallback dimension.
+// NewJWTExtractor panics if the fallback dimension is named like the claim.
+func NewJWTExtractor(opts ...JWTOption) ClientIDExtractor {
+	e := &jwtExtractor{
+		claim:    "sub",
+		fallback: IPDimension(NewIPExtractor()),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.fallback.Name == e.claim {
+		panic(fmt.Sprintf("middleware: JWT fallback dimension has the same name as claim %q", e.claim))
+	}
+
+	return e.extract
+}
+
+func (e *jwtExtractor) extract(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return e.fallbackKey(r)
+	}
+
+	claims, err := e.verify(strings.TrimSpace(token))
+	if err != nil {
+		return e.fallbackKey(r)
+	}
+
+	var value string
+	switch v := claims[e.claim].(type) {
+	case string:
+		value = v
+	case json.Number:
+		value = v.String()
+	}
+	if value == "" {
+		return e.fallbackKey(r)
+	}
+	return url.QueryEscape(e.claim) + "=" + url.QueryEscape(value)
+}
+
+func (e *jwtExtractor) fallbackKey(r *http.Request) string {
+	return url.QueryEscape(e.fallback.Name) + "=" + url.QueryEscape(e.fallback.Extract(r))
+}
+
+var errInvalidToken = errors.New("middleware: invalid token")
+
+// verify checks the token's signature and validity period and returns its
+// claims.
+func (e *jwtExtractor) verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if !e.verifySignature(header.Alg, header.Kid, signed, signature) {
+		return nil, errInvalidToken
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	exp, hasExp, err := numericDate(claims, "exp")
+	if err != nil {
+		return nil, err
+	}
+	nbf, hasNbf, err := numericDate(claims, "nbf")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if hasExp && !now.Before(exp) {
+		return nil, errInvalidToken
+	}
+	if hasNbf && now.Before(nbf) {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+func (e *jwtExtractor) verifySignature(alg, kid string, signed, signature []byte) bool {
+	if len(alg) != 5 {
+		return false
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return false
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	for _, k := range e.keys {
+		if k.id != "" && k.id != kid {
+			continue
+		}
+
+		switch key := k.key.(type) {
+		case []byte:
+			if alg[:2] != "HS" {
+				continue
+			}
+			mac := hmac.New(hash.New, key)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return true
+			}
+		case *rsa.PublicKey:
+			switch alg[:2] {
+			case "RS":
+				if rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+					return true
+				}
+			case "PS":
+				if rsa.VerifyPSS(key, hash, digest, signature, nil) == nil {
+					return true
+				}
+			}
+		case *ecdsa.PublicKey:
+			bits := key.Curve.Params().BitSize
+			size := (bits + 7) / 8
+			if alg[:2] != "ES" || bits != ecdsaBits[hash] || len(signature) != 2*size {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ecdsaBits maps the hash of an ES algorithm to the size of its curve.
+var ecdsaBits = map[crypto.Hash]int{
+	crypto.SHA256: 256,
+	crypto.SHA384: 384,
+	crypto.SHA512: 521,
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errInvalidToken
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return errInvalidToken
+	}
+	return nil
+}
+
+// numericDate returns the time in the named claim and whether the claim is
+// present. A claim that is present but not a NumericDate makes the token invalid.
+func numericDate(claims map[string]any, name string) (time.Time, bool, error) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false, errInvalidToken
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, errInvalidToken
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), true, nil
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var hmacSecret = []byte("secret")
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken builds a JWT with the given header and claims signed by sign.
+func signToken(t *testing.T, header, claims map[string]any, sign func([]byte) []byte) string {
+	t.Helper()
+
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(data []byte) []byte {
+	mac := hmac.New(sha256.New, hmacSecret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func hmacToken(t *testing.T, claims map[string]any) string {
+	return signToken(t, map[string]any{"alg": "HS256", "typ": "JWT"}, claims, hs256)
+}
+
+func extractWithToken(extract ClientIDExtractor, token string) string {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return extract(req)
+}
+
+func TestJWTExtractor_HMAC(t *testing.T) {
+	extract := NewJWTExtractor(WithHMACKey("", hmacSecret))
+
+	token := hmacToken(t, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	if got := extractWithToken(extract, token); got != "sub=alice" {
+		t.Errorf("Expected sub=alice, got %s", got)
+	}
+}
+
+func TestJWTExtractor_RSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract := NewJWTExtractor(WithRSAKey("", &key.PublicKey))
+
+	for _, alg := range []string{"RS256", "PS256"} {
+		token := signToken(t, map[string]any{"alg": alg}, map[string]any{"sub": "alice"}, func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			var sig []byte
+			if alg == "RS256" {
+				sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			} else {
+				sig, err = rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		})
+
+		if got := extractWithToken(extract, token); got != "sub=alice" {
+			t.Errorf("%s: expected sub=alice, got %s", alg, got)
+		}
+	}
+}
+
+func TestJWTExtractor_ECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract := NewJWTExtractor(WithECDSAKey("", &key.PublicKey))
+
+	sign := func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	}
+
+	token := signToken(t, map[string]any{"alg": "ES256"}, map[string]any{"sub": "alice"}, sign)
+	if got := extractWithToken(extract, token); got != "sub=alice" {
+		t.Errorf("Expected sub=alice, got %s", got)
+	}
+
+	token = signToken(t, map[string]any{"alg": "ES384"}, map[string]any{"sub": "alice"}, sign)
+	if got := extractWithToken(extract, token); got != "ip=192.0.2.1" {
+		t.Errorf("Expected algorithm not matching the curve to fall back, got %s", got)
+	}
+}
+
+func TestJWTExtractor_KeyID(t *testing.T) {
+	extract := NewJWTExtractor(
+		WithHMACKey("old", []byte("old-secret")),
+		WithHMACKey("current", hmacSecret),
+	)
+
+	token := signToken(t, map[string]any{"alg": "HS256", "kid": "current"}, map[string]any{"sub": "alice"}, hs256)
+	if got := extractWithToken(extract, token); got != "sub=alice" {
+		t.Errorf("Expected sub=alice, got %s", got)
+	}
+
+	token = signToken(t, map[string]any{"alg": "HS256", "kid": "old"}, map[string]any{"sub": "alice"}, hs256)
+	if got := extractWithToken(extract, token); got != "ip=192.0.2.1" {
+		t.Errorf("Expected token signed with a different key than its kid to fall back, got %s", got)
+	}
+}
+
+func TestJWTExtractor_Claim(t *testing.T) {
+	extract := NewJWTExtractor(WithHMACKey("", hmacSecret), WithJWTClaim("tenant_id"))
+
+	token := hmacToken(t, map[string]any{"sub": "alice", "tenant_id": 42})
+	if got := extractWithToken(extract, token); got != "tenant_id=42" {
+		t.Errorf("Expected tenant_id=42, got %s", got)
+	}
+}
+
+func TestJWTExtractor_FallsBack(t *testing.T) {
+	extract := NewJWTExtractor(WithHMACKey("", hmacSecret))
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"no token", ""},
+		{"malformed", "not-a-token"},
+		{"bad signature", signToken(t, map[string]any{"alg": "HS256"}, map[string]any{"sub": "alice"}, func([]byte) []byte {
+			return []byte("forged")
+		})},
+		{"alg none", signToken(t, map[string]any{"alg": "none"}, map[string]any{"sub": "alice"}, func([]byte) []byte {
+			return nil
+		})},
+		{"expired", hmacToken(t, map[string]any{"sub": "alice", "exp": now.Add(-time.Minute).Unix()})},
+		{"not yet valid", hmacToken(t, map[string]any{"sub": "alice", "nbf": now.Add(time.Minute).Unix()})},
+		{"string exp", hmacToken(t, map[string]any{"sub": "alice", "exp": "never"})},
+		{"null exp", hmacToken(t, map[string]any{"sub": "alice", "exp": nil})},
+		{"string nbf", hmacToken(t, map[string]any{"sub": "alice", "nbf": "0"})},
+		{"missing claim", hmacToken(t, map[string]any{"name": "alice"})},
+		{"non-scalar claim", hmacToken(t, map[string]any{"sub": []string{"alice"}})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractWithToken(extract, tt.token); got != "ip=192.0.2.1" {
+				t.Errorf("Expected fallback to IP, got %s", got)
+			}
+		})
+	}
+}
+
+func TestJWTExtractor_CustomFallback(t *testing.T) {
+	extract := NewJWTExtractor(
+		WithHMACKey("", hmacSecret),
+		WithJWTFallback(IPDimension(NewIPExtractor(WithIPv4Prefix(24)))),
+	)
+
+	if got := extractWithToken(extract, ""); got != "ip=192.0.2.0%2F24" {
+		t.Errorf("Expected custom fallback, got %s", got)
+	}
+}
+
+func TestJWTExtractor_FallbackCannotImpersonateClaim(t *testing.T) {
+	extract := NewJWTExtractor(WithHMACKey("", hmacSecret))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "sub=alice")
+
+	if got := extract(req); got != "ip=192.0.2.1" {
+		t.Errorf("Expected the forged header to be ignored, got %s", got)
+	}
+
+	forged := NewJWTExtractor(WithHMACKey("", hmacSecret), WithJWTFallback(IPDimension(DefaultClientIDExtractor)))
+	if got := forged(req); got == "sub=alice" {
+		t.Error("Expected fallback keys to be namespaced apart from claim keys")
+	}
+}
+
+func TestJWTExtractor_FallbackNamedLikeClaimPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewJWTExtractor to panic")
+		}
+	}()
+
+	NewJWTExtractor(WithJWTFallback(CustomDimension("sub", DefaultClientIDExtractor)))
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// KeyDimension is one named request attribute contributing to a composite
+// client key.
+type KeyDimension struct {
+	Name    string
+	Extract ClientIDExtractor
+}
+
+// CustomDimension returns a dimension named name whose value is computed by fn.
+func CustomDimension(name string, fn ClientIDExtractor) KeyDimension {
+	return KeyDimension{Name: name, Extract: fn}
+}
+
+// IPDimension keys on the client IP as returned by extractor, such as
+// DefaultClientIDExtractor or an extractor from NewIPExtractor.
+func IPDimension(extractor ClientIDExtractor) KeyDimension {
+	return KeyDimension{Name: "ip", Extract: extractor}
+}
+
+// HeaderDimension keys on the value of a request header. The dimension is named
+// after the header in lower case.
+func HeaderDimension(header string) KeyDimension {
+	return KeyDimension{
+		Name: strings.ToLower(header),
+		Extract: func(r *http.Request) string {
+			return r.Header.Get(header)
+		},
+	}
+}
+
+// MethodDimension keys on the request method.
+func MethodDimension() KeyDimension {
+	return KeyDimension{
+		Name: "method",
+		Extract: func(r *http.Request) string {
+			return r.Method
+		},
+	}
+}
+
+// RouteDimension keys on the http.ServeMux pattern that matched the request,
+// such as "/items/{id}", so that all items share one key. The pattern is only
+// known when the middleware wraps handlers registered on a ServeMux; otherwise
+// the request path is used.
+func RouteDimension() KeyDimension {
+	return KeyDimension{
+		Name: "route",
+		Extract: func(r *http.Request) string {
+			if r.Pattern != "" {
+				return r.Pattern
+			}
+			return r.URL.Path
+		},
+	}
+}
+
+// NewCompositeExtractor returns a ClientIDExtractor that combines several
+// request attributes into one key, for example:
+//
+//	ip=192.0.2.1&x-api-key=abc&method=POST
+//
+// Names and values are escaped, so keys built from different values can never
+// collide. Because the key names each dimension, it is readable in the
+// limiter's logs.
+func NewCompositeExtractor(dims ...KeyDimension) ClientIDExtractor {
+	return func(r *http.Request) string {
+		var b strings.Builder
+		for i, dim := range dims {
+			if i > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(dim.Name))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(dim.Extract(r)))
+		}
+		return b.String()
+	}
+}
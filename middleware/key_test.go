@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+)
+
+func TestCompositeExtractor(t *testing.T) {
+	extract := NewCompositeExtractor(
+		IPDimension(DefaultClientIDExtractor),
+		HeaderDimension("X-Tenant"),
+		MethodDimension(),
+		RouteDimension(),
+	)
+
+	req := httptest.NewRequest("POST", "/items/42", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Tenant", "acme")
+
+	want := "ip=192.0.2.1&x-tenant=acme&method=POST&route=%2Fitems%2F42"
+	if got := extract(req); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestCompositeExtractor_NoCollisions(t *testing.T) {
+	extract := NewCompositeExtractor(HeaderDimension("X-A"), HeaderDimension("X-B"))
+
+	key := func(a, b string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-A", a)
+		req.Header.Set("X-B", b)
+		return extract(req)
+	}
+
+	pairs := [][2]string{
+		{"a&x-b=b", ""},
+		{"a", "b"},
+		{"a=", "b"},
+		{"a", "=b"},
+		{"a|b", ""},
+		{"a%26", "b"},
+	}
+	seen := make(map[string][2]string)
+	for _, p := range pairs {
+		k := key(p[0], p[1])
+		if prev, ok := seen[k]; ok {
+			t.Errorf("Values %q and %q produced the same key %s", prev, p, k)
+		}
+		seen[k] = p
+	}
+}
+
+func TestCompositeExtractor_RoutePattern(t *testing.T) {
+	var got string
+	extract := NewCompositeExtractor(RouteDimension())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		got = extract(r)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/42", nil))
+
+	if want := "route=GET+%2Fitems%2F%7Bid%7D"; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestCompositeExtractor_DimensionsInLogs(t *testing.T) {
+	var buf bytes.Buffer
+	limiter := ratelimiter.New(
+		ratelimiter.WithMaxRequests(1),
+		ratelimiter.WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	)
+	middleware := NewRateLimiterMiddleware(limiter, WithClientIDExtractor(NewCompositeExtractor(
+		IPDimension(DefaultClientIDExtractor),
+		CustomDimension("tenant", func(r *http.Request) string { return "acme" }),
+	)))
+
+	serveN(middleware.Handler(okHandler()), "GET", "/", 2)
+
+	if want := `"client_id":"ip=192.168.1.1&tenant=acme"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected log to contain %s, got %s", want, buf.String())
+	}
+}
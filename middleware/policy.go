@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+)
+
+// WithPolicy applies limiter, instead of the middleware's default limiter, to
+// requests matching pattern. Patterns use http.ServeMux syntax, such as
+// "POST /login" or "GET /search/{query}", and the most specific matching pattern
+// wins. Once a policy is configured, every client key, including those of the
+// default limiter, is prefixed with "<pattern>|" (or "default|"), so policies
+// may share a limiter or storage without sharing quota.
+//
+// Like http.ServeMux.Handle, WithPolicy panics if pattern is invalid or
+// conflicts with a pattern already registered. It also panics if pattern
+// contains "|", which separates the namespace from the client ID.
+func WithPolicy(pattern string, limiter *ratelimiter.RateLimiter) MiddlewareOption {
+	if strings.Contains(pattern, "|") {
+		panic(fmt.Sprintf("middleware: policy pattern %q contains \"|\"", pattern))
+	}
+	return func(m *RateLimiterMiddleware) {
+		if m.routes == nil {
+			m.routes = http.NewServeMux()
+			m.policies = make(map[string]*ratelimiter.RateLimiter)
+		}
+		m.routes.Handle(pattern, http.NotFoundHandler())
+		m.policies[pattern] = limiter
+	}
+}
+
+// policyFor returns the name and limiter of the policy matching r, falling back
+// to the default limiter.
+func (m *RateLimiterMiddleware) policyFor(r *http.Request) (string, *ratelimiter.RateLimiter) {
+	if m.routes != nil {
+		if _, pattern := m.routes.Handler(r); pattern != "" {
+			if limiter, ok := m.policies[pattern]; ok {
+				return pattern, limiter
+			}
+		}
+	}
+	return policyName, m.limiter
+}
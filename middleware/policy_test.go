@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func serveN(handler http.Handler, method, target string, n int) int {
+	code := 0
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		code = rec.Code
+	}
+	return code
+}
+
+func TestPolicy_MatchesMethodAndPath(t *testing.T) {
+	middleware := NewRateLimiterMiddleware(
+		ratelimiter.New(ratelimiter.WithMaxRequests(100)),
+		WithPolicy("POST /login", ratelimiter.New(ratelimiter.WithMaxRequests(2))),
+		WithPolicy("GET /search", ratelimiter.New(ratelimiter.WithMaxRequests(5))),
+	)
+	handler := middleware.Handler(okHandler())
+
+	if code := serveN(handler, "POST", "/login", 2); code != http.StatusOK {
+		t.Errorf("Expected login requests within the limit to pass, got %d", code)
+	}
+	if code := serveN(handler, "POST", "/login", 1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected third login to be limited, got %d", code)
+	}
+
+	if code := serveN(handler, "GET", "/search", 5); code != http.StatusOK {
+		t.Errorf("Expected search to have its own limit, got %d", code)
+	}
+	if code := serveN(handler, "GET", "/search", 1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected sixth search to be limited, got %d", code)
+	}
+
+	if code := serveN(handler, "GET", "/login", 10); code != http.StatusOK {
+		t.Errorf("Expected GET /login to use the default limiter, got %d", code)
+	}
+}
+
+func TestPolicy_MostSpecificPatternWins(t *testing.T) {
+	middleware := NewRateLimiterMiddleware(
+		ratelimiter.New(ratelimiter.WithMaxRequests(100)),
+		WithPolicy("/api/", ratelimiter.New(ratelimiter.WithMaxRequests(10))),
+		WithPolicy("POST /api/upload/{name}", ratelimiter.New(ratelimiter.WithMaxRequests(1))),
+	)
+	handler := middleware.Handler(okHandler())
+
+	serveN(handler, "POST", "/api/upload/a.txt", 1)
+	if code := serveN(handler, "POST", "/api/upload/b.txt", 1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected wildcard pattern to share one quota, got %d", code)
+	}
+	if code := serveN(handler, "GET", "/api/items", 10); code != http.StatusOK {
+		t.Errorf("Expected /api/ subtree to use its own limit, got %d", code)
+	}
+	if code := serveN(handler, "GET", "/api/items", 1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected /api/ subtree to be limited, got %d", code)
+	}
+}
+
+func TestPolicy_SeparateKeyNamespaces(t *testing.T) {
+	shared := ratelimiter.New(
+		ratelimiter.WithMaxRequests(1),
+		ratelimiter.WithStorage(storage.NewMemoryStorage()),
+	)
+	middleware := NewRateLimiterMiddleware(
+		shared,
+		WithPolicy("/a", shared),
+		WithPolicy("/b", shared),
+	)
+	handler := middleware.Handler(okHandler())
+
+	for _, path := range []string{"/a", "/b", "/other"} {
+		if code := serveN(handler, "GET", path, 1); code != http.StatusOK {
+			t.Errorf("Expected first request to %s to pass despite the shared limiter, got %d", path, code)
+		}
+	}
+	if code := serveN(handler, "GET", "/a", 1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected second request to /a to be limited, got %d", code)
+	}
+}
+
+func TestPolicy_DefaultKeysCannotReachPolicies(t *testing.T) {
+	shared := ratelimiter.New(
+		ratelimiter.WithMaxRequests(1),
+		ratelimiter.WithStorage(storage.NewMemoryStorage()),
+	)
+	middleware := NewRateLimiterMiddleware(shared, WithPolicy("POST /login", shared))
+	handler := middleware.Handler(okHandler())
+
+	forged := httptest.NewRequest("GET", "/other", nil)
+	forged.Header.Set("X-Forwarded-For", "POST /login|192.0.2.1")
+	handler.ServeHTTP(httptest.NewRecorder(), forged)
+
+	req := httptest.NewRequest("POST", "/login", nil)
+	req.Header.Set("X-Forwarded-For", "192.0.2.1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected a forged default key not to use up the /login quota, got %d", rec.Code)
+	}
+}
+
+func TestPolicy_PatternWithSeparatorPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a pattern containing | to panic")
+		}
+	}()
+
+	WithPolicy("/a|b", ratelimiter.New())
+}
+
+func TestPolicy_RateLimitHeaders(t *testing.T) {
+	middleware := NewRateLimiterMiddleware(
+		ratelimiter.New(ratelimiter.WithMaxRequests(100)),
+		WithPolicy("POST /login", ratelimiter.New(
+			ratelimiter.WithMaxRequests(5),
+			ratelimiter.WithWindowDuration(time.Hour),
+		)),
+		WithRateLimitHeaders(HeaderIETF),
+	)
+	handler := middleware.Handler(okHandler())
+
+	req := httptest.NewRequest("POST", "/login", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("RateLimit-Policy"); got != `"POST /login";q=5;w=3600` {
+		t.Errorf("Unexpected RateLimit-Policy header: %s", got)
+	}
+}
+
+func TestPolicy_InvalidPatternPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected conflicting patterns to panic")
+		}
+	}()
+
+	limiter := ratelimiter.New()
+	NewRateLimiterMiddleware(limiter, WithPolicy("POST /login", limiter), WithPolicy("POST /login", limiter))
+}
@@ -0,0 +1,30 @@
+package ratelimiter
+
+import "time"
+
+// Clock is the limiter's source of time. Tests can substitute a fake clock,
+// such as ratelimitertest.FakeClock, to advance time deterministically.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func WithClock(clock Clock) Option {
+	return func(rl *RateLimiter) {
+		rl.clock = clock
+	}
+}
+
+func (rl *RateLimiter) Clock() Clock {
+	return rl.clock
+}
@@ -0,0 +1,96 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// FailurePolicy decides what happens to a request when the storage fails and no
+// fallback storage is configured.
+type FailurePolicy int
+
+const (
+	// FailOpen allows requests while the storage is unavailable, favouring
+	// availability.
+	FailOpen FailurePolicy = iota
+	// FailClosed denies requests while the storage is unavailable, favouring
+	// abuse protection.
+	FailClosed
+)
+
+func (p FailurePolicy) String() string {
+	switch p {
+	case FailOpen:
+		return "fail_open"
+	case FailClosed:
+		return "fail_closed"
+	default:
+		return fmt.Sprintf("FailurePolicy(%d)", int(p))
+	}
+}
+
+func WithFailurePolicy(policy FailurePolicy) Option {
+	return func(rl *RateLimiter) {
+		rl.failurePolicy = policy
+	}
+}
+
+// WithFallbackStorage makes the limiter enforce its limits against a local
+// storage, typically storage.NewMemoryStorage(), whenever the primary storage
+// fails. Each instance then limits on its own until the primary recovers.
+func WithFallbackStorage(storage Storage) Option {
+	return func(rl *RateLimiter) {
+		rl.fallbackStorage = AdaptStorage(storage)
+	}
+}
+
+// storageFailure decides a request the primary storage could not, using the
+// fallback storage if there is one and the failure policy otherwise.
+func (rl *RateLimiter) storageFailure(ctx context.Context, clientID string, n int, err error) (*Result, error) {
+	rl.logStorageError(clientID, err)
+	err = fmt.Errorf("ratelimiter: storage: %w", err)
+
+	if rl.fallback != nil {
+		result, fallbackErr := rl.fallback.AllowNContext(ctx, clientID, n)
+		result.Degraded = true
+		if fallbackErr != nil {
+			return result, fallbackErr
+		}
+		return result, err
+	}
+
+	// Like StatusContext, report the quota the failure policy grants: none
+	// until the retry when failing closed, all of it when failing open.
+	now := rl.clock.Now()
+	if rl.failurePolicy == FailClosed {
+		return &Result{
+			Allowed:       false,
+			Limit:         rl.limit(),
+			RetryAfter:    now.Add(time.Second),
+			RetryAfterSec: 1,
+			ErrorMessage:  rl.errorMessage,
+			ResetAt:       now.Add(time.Second),
+			Degraded:      true,
+		}, err
+	}
+
+	return &Result{
+		Allowed:   true,
+		Limit:     rl.limit(),
+		Remaining: rl.limit(),
+		ResetAt:   now,
+		Degraded:  true,
+	}, err
+}
+
+func (rl *RateLimiter) logStorageError(clientID string, err error) {
+	rl.logger.Error("Rate limit storage failed",
+		slog.String("client_id", clientID),
+		slog.String("algorithm", rl.algorithm.String()),
+		slog.String("failure_policy", rl.failurePolicy.String()),
+		slog.Bool("fallback", rl.fallback != nil),
+		slog.Any("error", err),
+	)
+}
@@ -0,0 +1,200 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func TestFailurePolicy_String(t *testing.T) {
+	if FailOpen.String() != "fail_open" {
+		t.Errorf("Expected fail_open, got %s", FailOpen)
+	}
+	if FailClosed.String() != "fail_closed" {
+		t.Errorf("Expected fail_closed, got %s", FailClosed)
+	}
+}
+
+func TestFailOpen_IsDefault(t *testing.T) {
+	rl := New(WithStorageV2(&failingStorage{err: errUnavailable}), WithLogger(quietLogger))
+
+	result, err := rl.AllowContext(context.Background(), "client")
+	if err == nil {
+		t.Error("Expected storage error")
+	}
+	if !result.Allowed {
+		t.Error("Expected fail-open to allow the request")
+	}
+	if !result.Degraded {
+		t.Error("Expected result to be degraded")
+	}
+	if result.Remaining != result.Limit || result.ResetAt.IsZero() {
+		t.Errorf("Expected the full quota resetting now, got %d/%d reset at %v", result.Remaining, result.Limit, result.ResetAt)
+	}
+}
+
+func TestFailClosed(t *testing.T) {
+	rl := New(
+		WithStorageV2(&failingStorage{err: errUnavailable}),
+		WithFailurePolicy(FailClosed),
+		WithErrorMessage("Try later"),
+		WithLogger(quietLogger),
+	)
+
+	result, err := rl.AllowContext(context.Background(), "client")
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected storage error, got %v", err)
+	}
+	if result.Allowed {
+		t.Error("Expected fail-closed to deny the request")
+	}
+	if !result.Degraded {
+		t.Error("Expected result to be degraded")
+	}
+	if result.ErrorMessage != "Try later" {
+		t.Errorf("Expected configured error message, got %q", result.ErrorMessage)
+	}
+	if result.RetryAfterSec != 1 {
+		t.Errorf("Expected RetryAfterSec 1, got %d", result.RetryAfterSec)
+	}
+	if result.Remaining != 0 || !result.ResetAt.Equal(result.RetryAfter) {
+		t.Errorf("Expected no quota until the retry, got %d reset at %v", result.Remaining, result.ResetAt)
+	}
+
+	status, _ := rl.StatusContext(context.Background(), "client")
+	if status.Remaining != 0 {
+		t.Errorf("Expected no remaining quota while failing closed, got %d", status.Remaining)
+	}
+}
+
+func TestFallbackStorage(t *testing.T) {
+	rl := New(
+		WithStorageV2(&failingStorage{err: errUnavailable}),
+		WithFallbackStorage(storage.NewMemoryStorage()),
+		WithFailurePolicy(FailClosed),
+		WithMaxRequests(2),
+		WithLogger(quietLogger),
+	)
+
+	for i := 0; i < 2; i++ {
+		result, err := rl.AllowContext(context.Background(), "client")
+		if !errors.Is(err, errUnavailable) {
+			t.Errorf("Expected storage error, got %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("Request %d: expected fallback to allow", i)
+		}
+		if !result.Degraded {
+			t.Errorf("Request %d: expected result to be degraded", i)
+		}
+	}
+
+	result := rl.Allow("client")
+	if result.Allowed {
+		t.Error("Expected fallback to enforce the limit")
+	}
+	if !result.Degraded {
+		t.Error("Expected denied result to be degraded")
+	}
+
+	status, _ := rl.StatusContext(context.Background(), "client")
+	if !status.Blocked {
+		t.Error("Expected status to come from the fallback storage")
+	}
+
+	if err := rl.Wait(context.Background(), "other"); err != nil {
+		t.Errorf("Expected Wait to use the fallback storage, got %v", err)
+	}
+}
+
+func TestHealthyStorage_NotDegraded(t *testing.T) {
+	rl := New(WithFallbackStorage(storage.NewMemoryStorage()))
+
+	if rl.Allow("client").Degraded {
+		t.Error("Expected healthy storage not to degrade results")
+	}
+}
+
+func TestStorageFailure_Logged(t *testing.T) {
+	var buf bytes.Buffer
+	rl := New(
+		WithStorageV2(&failingStorage{err: errUnavailable}),
+		WithFailurePolicy(FailClosed),
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	)
+
+	rl.Allow("client")
+
+	for _, want := range []string{`"msg":"Rate limit storage failed"`, `"failure_policy":"fail_closed"`, `"fallback":false`, `"error":"storage unavailable"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected log to contain %s, got %s", want, buf.String())
+		}
+	}
+}
+
+// recoveringStorage fails AppendToLog while failing is set and otherwise
+// delegates to the wrapped storage.
+type recoveringStorage struct {
+	StorageV2
+	failing bool
+}
+
+func (s *recoveringStorage) AppendToLog(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.RequestLog, bool, error) {
+	if s.failing {
+		return nil, false, errUnavailable
+	}
+	return s.StorageV2.AppendToLog(ctx, clientID, now, windowDuration, maxRequests, n)
+}
+
+func TestReservation_CancelDegraded(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback bool
+	}{
+		{"fail open", false},
+		{"fallback", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &recoveringStorage{StorageV2: AdaptStorage(storage.NewMemoryStorage())}
+			opts := []Option{
+				WithStorageV2(primary),
+				WithAlgorithm(SlidingLog),
+				WithMaxRequests(2),
+				WithLogger(quietLogger),
+			}
+			if tt.fallback {
+				opts = append(opts, WithFallbackStorage(storage.NewMemoryStorage()))
+			}
+			rl := New(opts...)
+
+			rl.Allow("client")
+			rl.Allow("client")
+
+			primary.failing = true
+			r := rl.Reserve("client")
+			if !r.OK() || !r.Result().Degraded {
+				t.Fatalf("Expected a degraded grant, got %+v", r.Result())
+			}
+
+			primary.failing = false
+			r.Cancel()
+
+			if rl.Allow("client").Allowed {
+				t.Error("Expected cancelling a degraded reservation not to refund the primary storage")
+			}
+			if tt.fallback {
+				if status := rl.fallback.Status("client"); status.Remaining != 2 {
+					t.Errorf("Expected the fallback storage to get the unit back, got %d remaining", status.Remaining)
+				}
+			}
+		})
+	}
+}
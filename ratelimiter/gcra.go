@@ -0,0 +1,66 @@
+package ratelimiter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+func (rl *RateLimiter) emissionInterval() time.Duration {
+	if rl.refillRate <= 0 {
+		return rl.windowDuration
+	}
+	return time.Duration(float64(time.Second) / rl.refillRate)
+}
+
+func (rl *RateLimiter) allowGCRA(ctx context.Context, clientID string, now time.Time, n int) (*Result, error) {
+	interval := rl.emissionInterval()
+	tat, allowed, err := rl.storage.UpdateArrivalTime(ctx, clientID, now, interval, rl.burst, n)
+	if err != nil {
+		return rl.storageFailure(ctx, clientID, n, err)
+	}
+
+	if !allowed {
+		retryAfter := tat.Add(interval * time.Duration(n-rl.burst))
+		retryAfterSec := int(math.Ceil(retryAfter.Sub(now).Seconds()))
+		if retryAfterSec < 1 {
+			retryAfterSec = 1
+		}
+
+		if rl.logOnExceedOnly {
+			rl.logger.Info("Rate limit exceeded",
+				slog.String("client_id", clientID),
+				slog.String("algorithm", rl.algorithm.String()),
+				slog.Int("limit", rl.burst),
+				slog.Time("retry_after", retryAfter),
+			)
+		}
+
+		return &Result{
+			Allowed:       false,
+			RequestsMade:  rl.burst,
+			Limit:         rl.burst,
+			RetryAfter:    retryAfter,
+			RetryAfterSec: retryAfterSec,
+			ErrorMessage:  rl.errorMessage,
+			ResetAt:       tat,
+		}, nil
+	}
+
+	remaining := 0
+	if interval > 0 {
+		remaining = int((interval*time.Duration(rl.burst) - tat.Sub(now)) / interval)
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &Result{
+		Allowed:      true,
+		RequestsMade: rl.burst - remaining,
+		Limit:        rl.burst,
+		Remaining:    remaining,
+		ResetAt:      tat,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter/ratelimitertest"
+)
+
+func TestAllowGCRA_Burst(t *testing.T) {
+	rl := New(
+		WithAlgorithm(GCRA),
+		WithMaxRequests(4),
+		WithWindowDuration(time.Hour),
+	)
+	clientID := "test-client"
+
+	for i := 1; i <= 4; i++ {
+		result := rl.Allow(clientID)
+		if !result.Allowed {
+			t.Errorf("Request %d should be allowed", i)
+		}
+		if result.Remaining != 4-i {
+			t.Errorf("Expected Remaining %d, got %d", 4-i, result.Remaining)
+		}
+	}
+
+	result := rl.Allow(clientID)
+	if result.Allowed {
+		t.Error("Request exceeding burst should not be allowed")
+	}
+
+	expected := time.Now().Add(15 * time.Minute)
+	if diff := result.RetryAfter.Sub(expected).Abs(); diff > time.Second {
+		t.Errorf("Expected RetryAfter one emission interval away, off by %v", diff)
+	}
+}
+
+func TestAllowGCRA_Refill(t *testing.T) {
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	rl := New(
+		WithAlgorithm(GCRA),
+		WithRefillRate(20),
+		WithBurst(1),
+		WithClock(clock),
+	)
+	clientID := "test-client"
+
+	if result := rl.Allow(clientID); !result.Allowed {
+		t.Error("First request should be allowed")
+	}
+	if result := rl.Allow(clientID); result.Allowed {
+		t.Error("Immediate second request should not be allowed")
+	}
+
+	clock.Advance(50 * time.Millisecond)
+
+	if result := rl.Allow(clientID); !result.Allowed {
+		t.Error("Request should be allowed after the emission interval")
+	}
+}
@@ -0,0 +1,69 @@
+package ratelimiter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+func (rl *RateLimiter) leakyBucketMaxDelay(interval time.Duration) time.Duration {
+	maxDelay := interval * time.Duration(rl.queueDepth-1)
+	if rl.maxQueueWait > 0 && rl.maxQueueWait < maxDelay {
+		maxDelay = rl.maxQueueWait
+	}
+	return maxDelay
+}
+
+func (rl *RateLimiter) allowLeakyBucket(ctx context.Context, clientID string, now time.Time, n int) (*Result, error) {
+	interval := rl.emissionInterval()
+	maxDelay := rl.leakyBucketMaxDelay(interval)
+
+	slot, allowed, err := rl.storage.ScheduleLeak(ctx, clientID, now, interval, maxDelay, n)
+	if err != nil {
+		return rl.storageFailure(ctx, clientID, n, err)
+	}
+	delay := slot.Sub(now)
+
+	queued := 0
+	if interval > 0 {
+		queued = int(math.Ceil(float64(delay) / float64(interval)))
+	}
+
+	if !allowed {
+		retryAfter := slot.Add(interval*time.Duration(n-1) - maxDelay)
+		retryAfterSec := int(math.Ceil(retryAfter.Sub(now).Seconds()))
+		if retryAfterSec < 1 {
+			retryAfterSec = 1
+		}
+
+		if rl.logOnExceedOnly {
+			rl.logger.Info("Rate limit exceeded",
+				slog.String("client_id", clientID),
+				slog.String("algorithm", rl.algorithm.String()),
+				slog.Int("queued", queued),
+				slog.Int("limit", rl.queueDepth),
+				slog.Time("retry_after", retryAfter),
+			)
+		}
+
+		return &Result{
+			Allowed:       false,
+			RequestsMade:  queued,
+			Limit:         rl.queueDepth,
+			RetryAfter:    retryAfter,
+			RetryAfterSec: retryAfterSec,
+			ErrorMessage:  rl.errorMessage,
+			ResetAt:       slot,
+		}, nil
+	}
+
+	return &Result{
+		Allowed:      true,
+		RequestsMade: queued + n,
+		Limit:        rl.queueDepth,
+		Remaining:    rl.queueDepth - queued - n,
+		Delay:        delay,
+		ResetAt:      slot.Add(interval * time.Duration(n)),
+	}, nil
+}
@@ -0,0 +1,85 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowLeakyBucket_Delays(t *testing.T) {
+	rl := New(
+		WithAlgorithm(LeakyBucket),
+		WithRefillRate(10),
+		WithQueueDepth(3),
+	)
+	clientID := "test-client"
+
+	for i := 0; i < 3; i++ {
+		result := rl.Allow(clientID)
+		if !result.Allowed {
+			t.Fatalf("Request %d should be queued", i+1)
+		}
+		expected := time.Duration(i) * 100 * time.Millisecond
+		if diff := (result.Delay - expected).Abs(); diff > 10*time.Millisecond {
+			t.Errorf("Request %d: expected delay ~%v, got %v", i+1, expected, result.Delay)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("Expected Remaining %d, got %d", 2-i, result.Remaining)
+		}
+	}
+
+	result := rl.Allow(clientID)
+	if result.Allowed {
+		t.Error("Request exceeding the queue depth should be rejected")
+	}
+	if result.Delay != 0 {
+		t.Errorf("Rejected request should have no delay, got %v", result.Delay)
+	}
+}
+
+func TestAllowLeakyBucket_MaxQueueWait(t *testing.T) {
+	rl := New(
+		WithAlgorithm(LeakyBucket),
+		WithRefillRate(1),
+		WithQueueDepth(10),
+		WithMaxQueueWait(1500*time.Millisecond),
+	)
+	clientID := "test-client"
+
+	for i := 0; i < 2; i++ {
+		if result := rl.Allow(clientID); !result.Allowed {
+			t.Errorf("Request %d should be queued", i+1)
+		}
+	}
+
+	result := rl.Allow(clientID)
+	if result.Allowed {
+		t.Error("Request that would wait longer than the max queue wait should be rejected")
+	}
+	if result.RetryAfterSec < 1 {
+		t.Errorf("Expected RetryAfterSec to be positive, got %d", result.RetryAfterSec)
+	}
+}
+
+func TestAllowLeakyBucket_CostCannotOverflowQueue(t *testing.T) {
+	rl := New(
+		WithAlgorithm(LeakyBucket),
+		WithRefillRate(10),
+		WithQueueDepth(3),
+	)
+	clientID := "test-client"
+
+	rl.Allow(clientID)
+	rl.Allow(clientID)
+
+	if result := rl.AllowN(clientID, 3); result.Allowed {
+		t.Error("Request that would overflow the queue depth should be rejected")
+	}
+
+	result := rl.Allow(clientID)
+	if !result.Allowed {
+		t.Fatal("Request fitting the queue should be queued")
+	}
+	if result.Remaining != 0 {
+		t.Errorf("Expected Remaining 0, got %d", result.Remaining)
+	}
+}
@@ -1,13 +1,24 @@
 package ratelimiter
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/iramosg/devin-ai-ratelimiter/storage"
 )
 
+// Storage is the original, error-free storage interface. It is enough for the
+// fixed window algorithm; costs other than 1 and the other algorithms need the
+// storage to also implement the matching optional interface below.
 type Storage interface {
 	GetClientData(clientID string) (*storage.ClientData, bool)
 	SetClientData(clientID string, data *storage.ClientData)
@@ -19,15 +30,86 @@ type Storage interface {
 	CheckAndIncrement(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration) (*storage.ClientData, bool)
 }
 
+// WeightedStorage is implemented by storages that can charge or release several
+// fixed window requests at once.
+type WeightedStorage interface {
+	CheckAndIncrementN(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*storage.ClientData, bool)
+}
+
+// TokenBucketStorage is implemented by storages that support TokenBucket.
+type TokenBucketStorage interface {
+	TakeToken(clientID string, now time.Time, refillRate float64, capacity int, n int) (*storage.TokenBucket, bool)
+}
+
+// SlidingWindowStorage is implemented by storages that support SlidingWindow.
+type SlidingWindowStorage interface {
+	SlidingWindowIncrement(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.WindowCounter, bool)
+}
+
+// SlidingLogStorage is implemented by storages that support SlidingLog.
+type SlidingLogStorage interface {
+	AppendToLog(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.RequestLog, bool)
+}
+
+// GCRAStorage is implemented by storages that support GCRA.
+type GCRAStorage interface {
+	UpdateArrivalTime(clientID string, now time.Time, emissionInterval time.Duration, burst int, n int) (time.Time, bool)
+}
+
+// LeakyBucketStorage is implemented by storages that support LeakyBucket.
+type LeakyBucketStorage interface {
+	ScheduleLeak(clientID string, now time.Time, leakInterval time.Duration, maxDelay time.Duration, n int) (time.Time, bool)
+}
+
+type Algorithm int
+
+const (
+	FixedWindow Algorithm = iota
+	TokenBucket
+	SlidingWindow
+	SlidingLog
+	GCRA
+	LeakyBucket
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case FixedWindow:
+		return "fixed_window"
+	case TokenBucket:
+		return "token_bucket"
+	case SlidingWindow:
+		return "sliding_window"
+	case SlidingLog:
+		return "sliding_log"
+	case GCRA:
+		return "gcra"
+	case LeakyBucket:
+		return "leaky_bucket"
+	default:
+		return fmt.Sprintf("Algorithm(%d)", int(a))
+	}
+}
+
 type RateLimiter struct {
-	storage         Storage
+	storage         StorageV2
 	maxRequests     int
 	windowDuration  time.Duration
 	blockDuration   time.Duration
 	errorMessage    string
 	includeJSON     bool
+	jsonFields      map[string]any
 	logger          *slog.Logger
 	logOnExceedOnly bool
+	algorithm       Algorithm
+	refillRate      float64
+	burst           int
+	queueDepth      int
+	maxQueueWait    time.Duration
+	failurePolicy   FailurePolicy
+	fallbackStorage StorageV2
+	fallback        *RateLimiter
+	clock           Clock
 }
 
 type Option func(*RateLimiter)
@@ -62,7 +144,21 @@ func WithIncludeJSON(include bool) Option {
 	}
 }
 
+// WithJSONFields adds fields to the JSON body of every denied request, for
+// example a link to the API's rate limit documentation.
+func WithJSONFields(fields map[string]any) Option {
+	return func(rl *RateLimiter) {
+		rl.jsonFields = fields
+	}
+}
+
 func WithStorage(storage Storage) Option {
+	return func(rl *RateLimiter) {
+		rl.storage = AdaptStorage(storage)
+	}
+}
+
+func WithStorageV2(storage StorageV2) Option {
 	return func(rl *RateLimiter) {
 		rl.storage = storage
 	}
@@ -80,9 +176,48 @@ func WithLogOnExceedOnly(logOnExceedOnly bool) Option {
 	}
 }
 
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(rl *RateLimiter) {
+		rl.algorithm = algorithm
+	}
+}
+
+// WithRefillRate sets the token bucket refill rate (or GCRA emission rate) in tokens per second.
+// When unset, the bucket refills maxRequests tokens per windowDuration.
+func WithRefillRate(tokensPerSecond float64) Option {
+	return func(rl *RateLimiter) {
+		rl.refillRate = tokensPerSecond
+	}
+}
+
+// WithBurst sets the token bucket capacity (or GCRA burst tolerance). When unset, it defaults to maxRequests.
+func WithBurst(burst int) Option {
+	return func(rl *RateLimiter) {
+		rl.burst = burst
+	}
+}
+
+// WithQueueDepth sets the leaky bucket capacity: how many of a client's requests
+// may be admitted but not yet drained. When unset, it defaults to the burst size.
+func WithQueueDepth(depth int) Option {
+	return func(rl *RateLimiter) {
+		rl.queueDepth = depth
+	}
+}
+
+// WithMaxQueueWait bounds how long a request may be delayed by the leaky bucket.
+// Zero means the wait is only bounded by the queue depth.
+func WithMaxQueueWait(wait time.Duration) Option {
+	return func(rl *RateLimiter) {
+		rl.maxQueueWait = wait
+	}
+}
+
+// New returns a limiter configured by opts. It panics if the storage, or the
+// fallback storage, does not implement the configured algorithm.
 func New(opts ...Option) *RateLimiter {
 	rl := &RateLimiter{
-		storage:         storage.NewMemoryStorage(),
+		storage:         AdaptStorage(storage.NewMemoryStorage()),
 		maxRequests:     100,
 		windowDuration:  time.Minute,
 		blockDuration:   time.Minute,
@@ -90,31 +225,180 @@ func New(opts ...Option) *RateLimiter {
 		includeJSON:     true,
 		logger:          slog.Default(),
 		logOnExceedOnly: true,
+		algorithm:       FixedWindow,
+		clock:           systemClock{},
 	}
 
 	for _, opt := range opts {
 		opt(rl)
 	}
 
+	for _, s := range []StorageV2{rl.storage, rl.fallbackStorage} {
+		if s != nil && !supports(s, rl.algorithm) {
+			panic(fmt.Sprintf("ratelimiter: storage %T does not support the %s algorithm", unwrap(s), rl.algorithm))
+		}
+	}
+
+	if rl.refillRate <= 0 && rl.windowDuration > 0 {
+		rl.refillRate = float64(rl.maxRequests) / rl.windowDuration.Seconds()
+	}
+	if rl.burst <= 0 {
+		rl.burst = rl.maxRequests
+	}
+	if rl.queueDepth <= 0 {
+		rl.queueDepth = rl.burst
+	}
+	if rl.fallbackStorage != nil {
+		fallback := *rl
+		fallback.storage = rl.fallbackStorage
+		fallback.fallbackStorage = nil
+		rl.fallback = &fallback
+	}
+
 	return rl
 }
 
+func (rl *RateLimiter) WindowDuration() time.Duration {
+	return rl.windowDuration
+}
+
+// limit returns the quota enforced by the configured algorithm.
+func (rl *RateLimiter) limit() int {
+	switch rl.algorithm {
+	case TokenBucket, GCRA:
+		return rl.burst
+	case LeakyBucket:
+		return rl.queueDepth
+	default:
+		return rl.maxRequests
+	}
+}
+
 type Result struct {
-	Allowed       bool
-	RequestsMade  int
-	Limit         int
-	RetryAfter    time.Time
-	RetryAfterSec int
-	ErrorMessage  string
+	Allowed       bool          `json:"allowed"`
+	RequestsMade  int           `json:"requests_made"`
+	Limit         int           `json:"limit"`
+	RetryAfter    time.Time     `json:"retry_after"`
+	RetryAfterSec int           `json:"retry_after_seconds"`
+	ErrorMessage  string        `json:"error,omitempty"`
+	Remaining     int           `json:"remaining"`
+	ResetAt       time.Time     `json:"reset_at"`
+	Delay         time.Duration `json:"-"`
+	Degraded      bool          `json:"degraded"`
+	// Extra holds additional fields for the JSON encoding, such as a request
+	// ID. Fields named like the standard ones are ignored.
+	Extra map[string]any `json:"-"`
 }
 
 func (rl *RateLimiter) Allow(clientID string) *Result {
-	now := time.Now()
+	return rl.AllowN(clientID, 1)
+}
+
+// ErrInvalidCost is returned for requests costing less than one unit.
+var ErrInvalidCost = errors.New("ratelimiter: cost must be at least 1")
 
-	data, allowed := rl.storage.CheckAndIncrement(clientID, now, rl.windowDuration, rl.maxRequests, rl.blockDuration)
+// ErrUnsupportedCost is returned for requests costing more than one unit when
+// the fixed window runs on a Storage that does not implement WeightedStorage.
+var ErrUnsupportedCost = errors.New("ratelimiter: storage does not support costs other than 1")
+
+// AllowN reports whether a request costing n units may proceed, charging n
+// against the client's quota if it does. Costs below 1 are denied.
+func (rl *RateLimiter) AllowN(clientID string, n int) *Result {
+	result, _ := rl.AllowNContext(context.Background(), clientID, n)
+	return result
+}
+
+func (rl *RateLimiter) AllowContext(ctx context.Context, clientID string) (*Result, error) {
+	return rl.AllowNContext(ctx, clientID, 1)
+}
+
+// AllowNContext is like AllowN but passes ctx to the storage. The result is never
+// nil: if the storage fails, the error is returned together with a degraded
+// result decided by the fallback storage or the failure policy. A cost below 1
+// is denied with ErrInvalidCost, and a cost the storage cannot charge with
+// ErrUnsupportedCost, both without reaching the storage or the failure policy.
+func (rl *RateLimiter) AllowNContext(ctx context.Context, clientID string, n int) (*Result, error) {
+	if n < 1 {
+		return rl.invalidCost(), ErrInvalidCost
+	}
+	if n > 1 && !supportsCost(rl.storage, rl.algorithm) {
+		rl.logger.Error("Rate limit cost not supported by storage",
+			slog.String("client_id", clientID),
+			slog.Int("cost", n),
+			slog.Any("error", ErrUnsupportedCost),
+		)
+		return rl.invalidCost(), ErrUnsupportedCost
+	}
+
+	result, err := rl.allowN(ctx, clientID, n)
+	if !result.Allowed && len(rl.jsonFields) > 0 {
+		result.Extra = maps.Clone(rl.jsonFields)
+	}
+	return result, err
+}
+
+// invalidCost returns the denial for a request whose cost cannot be charged.
+// Retrying does not help, so the result has no retry time.
+func (rl *RateLimiter) invalidCost() *Result {
+	return &Result{
+		Allowed:      false,
+		Limit:        rl.limit(),
+		ErrorMessage: rl.errorMessage,
+	}
+}
+
+func (rl *RateLimiter) allowN(ctx context.Context, clientID string, n int) (*Result, error) {
+	now := rl.clock.Now()
+
+	switch rl.algorithm {
+	case TokenBucket:
+		return rl.allowTokenBucket(ctx, clientID, now, n)
+	case SlidingWindow:
+		return rl.allowSlidingWindow(ctx, clientID, now, n)
+	case SlidingLog:
+		return rl.allowSlidingLog(ctx, clientID, now, n)
+	case GCRA:
+		return rl.allowGCRA(ctx, clientID, now, n)
+	case LeakyBucket:
+		return rl.allowLeakyBucket(ctx, clientID, now, n)
+	}
+
+	data, allowed, err := rl.storage.CheckAndIncrementN(ctx, clientID, now, rl.windowDuration, rl.maxRequests, rl.blockDuration, n)
+	if err != nil {
+		return rl.storageFailure(ctx, clientID, n, err)
+	}
+
+	if !allowed && data.BlockedUntil.IsZero() {
+		retryAfter := data.WindowStart.Add(rl.windowDuration)
+		retryAfterSec := int(math.Ceil(retryAfter.Sub(now).Seconds()))
+		if retryAfterSec < 1 {
+			retryAfterSec = 1
+		}
+
+		if rl.logOnExceedOnly {
+			rl.logger.Info("Rate limit exceeded",
+				slog.String("client_id", clientID),
+				slog.Int("requests_made", data.RequestCount),
+				slog.Int("cost", n),
+				slog.Int("limit", rl.maxRequests),
+				slog.Time("retry_after", retryAfter),
+			)
+		}
+
+		return &Result{
+			Allowed:       false,
+			RequestsMade:  data.RequestCount,
+			Limit:         rl.maxRequests,
+			RetryAfter:    retryAfter,
+			RetryAfterSec: retryAfterSec,
+			ErrorMessage:  rl.errorMessage,
+			Remaining:     max(0, rl.maxRequests-data.RequestCount),
+			ResetAt:       retryAfter,
+		}, nil
+	}
 
 	if !allowed {
-		retryAfterSec := int(time.Until(data.BlockedUntil).Seconds())
+		retryAfterSec := int(data.BlockedUntil.Sub(now).Seconds())
 		if retryAfterSec < 1 {
 			retryAfterSec = 1
 		}
@@ -135,7 +419,8 @@ func (rl *RateLimiter) Allow(clientID string) *Result {
 			RetryAfter:    data.BlockedUntil,
 			RetryAfterSec: retryAfterSec,
 			ErrorMessage:  rl.errorMessage,
-		}
+			ResetAt:       data.BlockedUntil,
+		}, nil
 	}
 
 	if data.RequestCount > rl.maxRequests {
@@ -157,21 +442,71 @@ func (rl *RateLimiter) Allow(clientID string) *Result {
 			RetryAfter:    data.BlockedUntil,
 			RetryAfterSec: retryAfterSec,
 			ErrorMessage:  rl.errorMessage,
-		}
+			ResetAt:       data.BlockedUntil,
+		}, nil
 	}
 
 	return &Result{
 		Allowed:      true,
 		RequestsMade: data.RequestCount,
 		Limit:        rl.maxRequests,
+		Remaining:    max(0, rl.maxRequests-data.RequestCount),
+		ResetAt:      data.WindowStart.Add(rl.windowDuration),
+	}, nil
+}
+
+// resultFields has the fields of Result without its MarshalJSON method.
+type resultFields Result
+
+// resultFieldNames holds the JSON names of the fields of Result, which Extra
+// cannot override.
+var resultFieldNames = func() map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeFor[Result]()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// MarshalJSON encodes the fields of the result as named by their struct tags,
+// followed by the Extra fields in key order. Extra fields whose value cannot
+// be encoded are left out.
+func (r Result) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(resultFields(r))
+	if err != nil || len(r.Extra) == 0 {
+		return data, err
+	}
+
+	names := make([]string, 0, len(r.Extra))
+	for name := range r.Extra {
+		if !resultFieldNames[name] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	data = data[:len(data)-1]
+	for _, name := range names {
+		value, err := json.Marshal(r.Extra[name])
+		if err != nil {
+			continue
+		}
+		key, _ := json.Marshal(name)
+		data = append(data, ',')
+		data = append(data, key...)
+		data = append(data, ':')
+		data = append(data, value...)
 	}
+	return append(data, '}'), nil
 }
 
+// FormatJSON returns the JSON encoding of the result, used as the body of a
+// denied response.
 func (r *Result) FormatJSON() string {
-	return fmt.Sprintf(`{"error":"%s","limit":%d,"requests_made":%d,"retry_after":"%s"}`,
-		r.ErrorMessage,
-		r.Limit,
-		r.RequestsMade,
-		r.RetryAfter.Format(time.RFC3339),
-	)
+	data, _ := json.Marshal(r)
+	return string(data)
 }
@@ -1,11 +1,16 @@
 package ratelimiter
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter/ratelimitertest"
 	"github.com/iramosg/devin-ai-ratelimiter/storage"
 )
 
@@ -129,7 +134,8 @@ func TestAllow_ExceedLimit(t *testing.T) {
 }
 
 func TestAllow_BlockedClient(t *testing.T) {
-	rl := New(WithMaxRequests(2), WithBlockDuration(100*time.Millisecond))
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	rl := New(WithMaxRequests(2), WithBlockDuration(100*time.Millisecond), WithClock(clock))
 	clientID := "test-client"
 
 	rl.Allow(clientID)
@@ -145,7 +151,7 @@ func TestAllow_BlockedClient(t *testing.T) {
 		t.Error("Blocked client should remain blocked")
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clock.Advance(150 * time.Millisecond)
 
 	result = rl.Allow(clientID)
 	if !result.Allowed {
@@ -154,14 +160,15 @@ func TestAllow_BlockedClient(t *testing.T) {
 }
 
 func TestAllow_WindowReset(t *testing.T) {
-	rl := New(WithMaxRequests(3), WithWindowDuration(100*time.Millisecond))
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	rl := New(WithMaxRequests(3), WithWindowDuration(100*time.Millisecond), WithClock(clock))
 	clientID := "test-client"
 
 	rl.Allow(clientID)
 	rl.Allow(clientID)
 	rl.Allow(clientID)
 
-	time.Sleep(150 * time.Millisecond)
+	clock.Advance(150 * time.Millisecond)
 
 	result := rl.Allow(clientID)
 	if !result.Allowed {
@@ -199,21 +206,121 @@ func TestAllow_MultipleClients(t *testing.T) {
 func TestResult_FormatJSON(t *testing.T) {
 	retryAfter := time.Date(2025, 2, 6, 14, 30, 0, 0, time.UTC)
 	result := &Result{
-		Allowed:      false,
-		RequestsMade: 120,
-		Limit:        100,
-		RetryAfter:   retryAfter,
-		ErrorMessage: "Rate limit exceeded",
+		Allowed:       false,
+		RequestsMade:  120,
+		Limit:         100,
+		RetryAfter:    retryAfter,
+		RetryAfterSec: 60,
+		ErrorMessage:  "Rate limit exceeded",
 	}
 
 	json := result.FormatJSON()
 
-	expectedJSON := `{"error":"Rate limit exceeded","limit":100,"requests_made":120,"retry_after":"2025-02-06T14:30:00Z"}`
+	expectedJSON := `{"allowed":false,"requests_made":120,"limit":100,"retry_after":"2025-02-06T14:30:00Z","retry_after_seconds":60,"error":"Rate limit exceeded","remaining":0,"reset_at":"0001-01-01T00:00:00Z","degraded":false}`
 	if json != expectedJSON {
 		t.Errorf("Expected JSON:\n%s\nGot:\n%s", expectedJSON, json)
 	}
 }
 
+func TestResult_FormatJSON_Escaping(t *testing.T) {
+	result := &Result{ErrorMessage: `Slow "down" \ wait`}
+
+	var body map[string]any
+	if err := json.Unmarshal([]byte(result.FormatJSON()), &body); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if body["error"] != `Slow "down" \ wait` {
+		t.Errorf("Expected error message to round-trip, got %v", body["error"])
+	}
+}
+
+func TestResult_FormatJSON_Extra(t *testing.T) {
+	result := &Result{
+		Limit:        10,
+		ErrorMessage: "Rate limit exceeded",
+		Extra: map[string]any{
+			"request_id": "abc",
+			"docs":       "https://example.com/limits",
+			"limit":      999,
+		},
+	}
+
+	got := result.FormatJSON()
+	if !strings.HasSuffix(got, `"degraded":false,"docs":"https://example.com/limits","request_id":"abc"}`) {
+		t.Errorf("Expected extra fields after the standard ones, got %s", got)
+	}
+	if !strings.Contains(got, `"limit":10,`) || strings.Contains(got, "999") {
+		t.Errorf("Expected extra fields not to override standard ones, got %s", got)
+	}
+
+	result.Extra = map[string]any{"bad": make(chan int), "request_id": "abc"}
+	if got := result.FormatJSON(); !strings.HasSuffix(got, `"degraded":false,"request_id":"abc"}`) || strings.Contains(got, "bad") {
+		t.Errorf("Expected only the unencodable extra field to be dropped, got %s", got)
+	}
+}
+
+func TestResult_MarshalJSON(t *testing.T) {
+	result := &Result{
+		Allowed:      true,
+		RequestsMade: 3,
+		Limit:        10,
+		Remaining:    7,
+		Delay:        time.Second,
+		Extra:        map[string]any{"request_id": "abc"},
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != result.FormatJSON() {
+		t.Errorf("Expected json.Marshal to match FormatJSON, got %s and %s", data, result.FormatJSON())
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["allowed"] != true || body["remaining"] != float64(7) || body["limit"] != float64(10) {
+		t.Errorf("Expected every result field to be encoded, got %s", data)
+	}
+	if body["request_id"] != "abc" {
+		t.Errorf("Expected Extra fields to be encoded, got %s", data)
+	}
+	if _, ok := body["error"]; ok {
+		t.Errorf("Expected no error field on an allowed result, got %s", data)
+	}
+	if _, ok := body["delay"]; ok {
+		t.Errorf("Expected no delay field, got %s", data)
+	}
+
+	if value, _ := json.Marshal(*result); string(value) != string(data) {
+		t.Errorf("Expected a Result value to encode like a pointer, got %s", value)
+	}
+}
+
+func TestWithJSONFields(t *testing.T) {
+	rl := New(
+		WithMaxRequests(1),
+		WithJSONFields(map[string]any{"docs": "https://example.com/limits"}),
+	)
+
+	if result := rl.Allow("client"); result.Extra != nil {
+		t.Errorf("Expected no extra fields on allowed results, got %v", result.Extra)
+	}
+
+	result := rl.Allow("client")
+	if !strings.Contains(result.FormatJSON(), `"docs":"https://example.com/limits"`) {
+		t.Errorf("Expected configured fields in JSON, got %s", result.FormatJSON())
+	}
+
+	result.Extra["request_id"] = "abc"
+	if next := rl.Allow("client"); next.Extra["request_id"] != nil {
+		t.Error("Expected each result to get its own copy of the fields")
+	}
+}
+
 func TestAllow_ConcurrentRequests(t *testing.T) {
 	rl := New(WithMaxRequests(100))
 	clientID := "concurrent-client"
@@ -297,3 +404,144 @@ func TestAllow_CustomErrorMessage(t *testing.T) {
 		t.Errorf("Expected error message '%s', got '%s'", customMessage, result.ErrorMessage)
 	}
 }
+
+func TestAllowN_FixedWindow(t *testing.T) {
+	rl := New(WithMaxRequests(10))
+	clientID := "test-client"
+
+	result := rl.AllowN(clientID, 7)
+	if !result.Allowed {
+		t.Error("Request costing 7 should be allowed")
+	}
+	if result.RequestsMade != 7 {
+		t.Errorf("Expected RequestsMade 7, got %d", result.RequestsMade)
+	}
+
+	result = rl.AllowN(clientID, 4)
+	if result.Allowed {
+		t.Error("Request pushing the total over the limit should not be allowed")
+	}
+}
+
+func TestAllowN_CostExceedsLimitOnFirstRequest(t *testing.T) {
+	rl := New(WithMaxRequests(5))
+
+	result := rl.AllowN("test-client", 6)
+	if result.Allowed {
+		t.Error("Request costing more than the limit should not be allowed")
+	}
+
+	if status := rl.Status("test-client"); status.Blocked || status.Remaining != 5 {
+		t.Errorf("Expected the denied request not to be charged, got %+v", status)
+	}
+	if !rl.AllowN("test-client", 5).Allowed {
+		t.Error("Request costing the full limit should still be allowed")
+	}
+}
+
+func TestAllowN_InvalidCost(t *testing.T) {
+	algorithms := []Algorithm{FixedWindow, TokenBucket, SlidingWindow, SlidingLog, GCRA, LeakyBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			rl := New(WithAlgorithm(algorithm), WithMaxRequests(5), WithLogger(quietLogger))
+
+			rl.AllowN("test-client", 5)
+			for _, n := range []int{0, -10} {
+				result, err := rl.AllowNContext(context.Background(), "test-client", n)
+				if !errors.Is(err, ErrInvalidCost) || result.Allowed {
+					t.Errorf("Expected cost %d to be denied with ErrInvalidCost, got %v/%v", n, result.Allowed, err)
+				}
+			}
+
+			if status := rl.Status("test-client"); status.Remaining != 0 {
+				t.Errorf("Expected invalid costs not to refund quota, got Remaining %d", status.Remaining)
+			}
+			if err := rl.WaitN(context.Background(), "test-client", 0); !errors.Is(err, ErrInvalidCost) {
+				t.Errorf("Expected WaitN to fail with ErrInvalidCost, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAllowN_Algorithms(t *testing.T) {
+	algorithms := []Algorithm{TokenBucket, SlidingWindow, SlidingLog, GCRA}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			rl := New(
+				WithAlgorithm(algorithm),
+				WithMaxRequests(10),
+				WithWindowDuration(time.Hour),
+			)
+			clientID := "test-client"
+
+			result := rl.AllowN(clientID, 8)
+			if !result.Allowed {
+				t.Error("Request costing 8 should be allowed")
+			}
+			if result.Remaining != 2 {
+				t.Errorf("Expected Remaining 2, got %d", result.Remaining)
+			}
+
+			result = rl.AllowN(clientID, 3)
+			if result.Allowed {
+				t.Error("Request costing 3 should not be allowed with 2 remaining")
+			}
+
+			result = rl.AllowN(clientID, 2)
+			if !result.Allowed {
+				t.Error("Request costing 2 should be allowed")
+			}
+		})
+	}
+}
+
+func TestAllow_RemainingAndResetAt(t *testing.T) {
+	rl := New(WithMaxRequests(3), WithWindowDuration(time.Minute), WithBlockDuration(time.Hour))
+	clientID := "test-client"
+
+	start := time.Now()
+	for i := 1; i <= 3; i++ {
+		result := rl.Allow(clientID)
+		if result.Remaining != 3-i {
+			t.Errorf("Expected Remaining %d, got %d", 3-i, result.Remaining)
+		}
+		if diff := result.ResetAt.Sub(start.Add(time.Minute)).Abs(); diff > time.Second {
+			t.Errorf("Expected ResetAt at the end of the window, off by %v", diff)
+		}
+	}
+
+	result := rl.Allow(clientID)
+	if result.Remaining != 0 {
+		t.Errorf("Expected Remaining 0 when denied, got %d", result.Remaining)
+	}
+	if !result.ResetAt.Equal(result.RetryAfter) {
+		t.Errorf("Expected ResetAt to match the end of the block, got %v", result.ResetAt)
+	}
+}
+
+func TestAllow_ResetAtAllAlgorithms(t *testing.T) {
+	algorithms := []Algorithm{TokenBucket, SlidingWindow, SlidingLog, GCRA, LeakyBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			rl := New(
+				WithAlgorithm(algorithm),
+				WithMaxRequests(2),
+				WithWindowDuration(time.Hour),
+			)
+			clientID := "test-client"
+
+			for i := 0; i < 3; i++ {
+				result := rl.Allow(clientID)
+				if !result.ResetAt.After(time.Now()) {
+					t.Errorf("Request %d: expected ResetAt in the future, got %v", i+1, result.ResetAt)
+				}
+				if result.Remaining < 0 || result.Remaining > 2 {
+					t.Errorf("Request %d: unexpected Remaining %d", i+1, result.Remaining)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimitertest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter/ratelimitertest"
+)
+
+var _ ratelimiter.Clock = (*ratelimitertest.FakeClock)(nil)
+
+func TestFakeClock_Advance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ratelimitertest.NewFakeClock(start)
+
+	clock.Advance(time.Minute)
+	if !clock.Now().Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected %v, got %v", start.Add(time.Minute), clock.Now())
+	}
+
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Errorf("Expected %v, got %v", start, clock.Now())
+	}
+}
+
+func TestFakeClock_After(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ratelimitertest.NewFakeClock(start)
+
+	ch := clock.After(time.Second)
+	if clock.Waiters() != 1 {
+		t.Errorf("Expected 1 waiter, got %d", clock.Waiters())
+	}
+
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("Expected After not to fire before the duration elapses")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case now := <-ch:
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("Expected %v, got %v", start.Add(time.Second), now)
+		}
+	default:
+		t.Fatal("Expected After to fire once the duration elapses")
+	}
+	if clock.Waiters() != 0 {
+		t.Errorf("Expected no waiters, got %d", clock.Waiters())
+	}
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Error("Expected After(0) to fire immediately")
+	}
+}
+
+func TestFakeClock_RateLimiter(t *testing.T) {
+	clock := ratelimitertest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	rl := ratelimiter.New(
+		ratelimiter.WithClock(clock),
+		ratelimiter.WithMaxRequests(1),
+		ratelimiter.WithWindowDuration(time.Minute),
+		ratelimiter.WithBlockDuration(time.Hour),
+	)
+
+	rl.Allow("client")
+	result := rl.Allow("client")
+	if result.Allowed {
+		t.Fatal("Expected second request to be denied")
+	}
+	if !result.RetryAfter.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("Expected RetryAfter %v, got %v", clock.Now().Add(time.Hour), result.RetryAfter)
+	}
+
+	clock.Advance(time.Hour + time.Second)
+	if !rl.Allow("client").Allowed {
+		t.Error("Expected request to be allowed once the block expires")
+	}
+}
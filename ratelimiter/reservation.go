@@ -0,0 +1,177 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrWaitExceedsDeadline = errors.New("ratelimiter: wait would exceed context deadline")
+
+type Reservation struct {
+	rl        *RateLimiter
+	clientID  string
+	n         int
+	result    *Result
+	timeToAct time.Time
+	// charged is the limiter whose storage took the units: the fallback for
+	// degraded results, or nil if the failure policy decided without storage.
+	charged *RateLimiter
+
+	mu       sync.Mutex
+	canceled bool
+}
+
+func (rl *RateLimiter) Reserve(clientID string) *Reservation {
+	return rl.ReserveN(clientID, 1)
+}
+
+// ReserveN attempts to take n units for the client. If the units were granted, the
+// caller must wait Delay() before acting (non-zero only for queueing algorithms) or
+// call Cancel() to give them back. Otherwise Delay() is the time until a retry may
+// succeed.
+func (rl *RateLimiter) ReserveN(clientID string, n int) *Reservation {
+	r, _ := rl.reserveN(context.Background(), clientID, n)
+	return r
+}
+
+// ReserveNContext is like ReserveN but passes ctx to the storage. The
+// reservation is never nil: if the storage fails, the error is returned together
+// with a reservation for the degraded result.
+func (rl *RateLimiter) ReserveNContext(ctx context.Context, clientID string, n int) (*Reservation, error) {
+	return rl.reserveN(ctx, clientID, n)
+}
+
+func (rl *RateLimiter) reserveN(ctx context.Context, clientID string, n int) (*Reservation, error) {
+	result, err := rl.AllowNContext(ctx, clientID, n)
+
+	timeToAct := result.RetryAfter
+	if result.Allowed {
+		timeToAct = rl.clock.Now().Add(result.Delay)
+	}
+
+	charged := rl
+	if result.Degraded {
+		charged = rl.fallback
+	}
+
+	return &Reservation{
+		rl:        rl,
+		clientID:  clientID,
+		n:         n,
+		result:    result,
+		timeToAct: timeToAct,
+		charged:   charged,
+	}, err
+}
+
+func (r *Reservation) OK() bool {
+	return r.result.Allowed
+}
+
+func (r *Reservation) Result() *Result {
+	return r.result
+}
+
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(r.rl.clock.Now())
+}
+
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	delay := r.timeToAct.Sub(now)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Cancel returns the reserved units to the storage that granted them: the
+// fallback storage for degraded reservations, or none if the failure policy
+// allowed the request without charging any storage. It is a no-op if the
+// reservation was not granted or has already been canceled.
+func (r *Reservation) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.result.Allowed || r.canceled {
+		return
+	}
+	r.canceled = true
+	if r.charged == nil {
+		return
+	}
+	if err := r.charged.release(context.Background(), r.clientID, r.n); err != nil {
+		r.charged.logStorageError(r.clientID, err)
+	}
+}
+
+func (rl *RateLimiter) Wait(ctx context.Context, clientID string) error {
+	return rl.WaitN(ctx, clientID, 1)
+}
+
+// WaitN blocks until n units are granted to the client or ctx is done. It fails
+// immediately if the wait would outlast the context deadline, or if the storage
+// returns an error and there is no fallback storage. A cost below 1 fails with
+// ErrInvalidCost, and a cost the storage cannot charge with ErrUnsupportedCost.
+func (rl *RateLimiter) WaitN(ctx context.Context, clientID string, n int) error {
+	if n < 1 {
+		return ErrInvalidCost
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := rl.reserveN(ctx, clientID, n)
+		if errors.Is(err, ErrUnsupportedCost) || (err != nil && rl.fallback == nil) {
+			return err
+		}
+		if !r.OK() && n > r.result.Limit {
+			return fmt.Errorf("ratelimiter: cost %d exceeds limit %d", n, r.result.Limit)
+		}
+
+		delay := r.Delay()
+		if delay == 0 && r.OK() {
+			return nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && delay > time.Until(deadline) {
+			r.Cancel()
+			return ErrWaitExceedsDeadline
+		}
+
+		select {
+		case <-rl.clock.After(delay):
+			if r.OK() {
+				return nil
+			}
+		case <-ctx.Done():
+			r.Cancel()
+			return ctx.Err()
+		}
+	}
+}
+
+func (rl *RateLimiter) release(ctx context.Context, clientID string, n int) error {
+	now := rl.clock.Now()
+
+	var err error
+	switch rl.algorithm {
+	case TokenBucket:
+		_, _, err = rl.storage.TakeToken(ctx, clientID, now, rl.refillRate, rl.burst, -n)
+	case SlidingWindow:
+		_, _, err = rl.storage.SlidingWindowIncrement(ctx, clientID, now, rl.windowDuration, rl.maxRequests, -n)
+	case SlidingLog:
+		_, _, err = rl.storage.AppendToLog(ctx, clientID, now, rl.windowDuration, rl.maxRequests, -n)
+	case GCRA:
+		_, _, err = rl.storage.UpdateArrivalTime(ctx, clientID, now, rl.emissionInterval(), rl.burst, -n)
+	case LeakyBucket:
+		_, _, err = rl.storage.ScheduleLeak(ctx, clientID, now, rl.emissionInterval(), 0, -n)
+	default:
+		_, _, err = rl.storage.CheckAndIncrementN(ctx, clientID, now, rl.windowDuration, rl.maxRequests, rl.blockDuration, -n)
+	}
+	return err
+}
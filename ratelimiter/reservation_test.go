@@ -0,0 +1,144 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter/ratelimitertest"
+)
+
+func TestReserve_Granted(t *testing.T) {
+	rl := New(WithMaxRequests(2))
+	clientID := "test-client"
+
+	r := rl.Reserve(clientID)
+	if !r.OK() {
+		t.Error("Expected reservation to be granted")
+	}
+	if r.Delay() != 0 {
+		t.Errorf("Expected no delay, got %v", r.Delay())
+	}
+}
+
+func TestReserve_DeniedReportsDelay(t *testing.T) {
+	rl := New(WithAlgorithm(SlidingLog), WithMaxRequests(1), WithWindowDuration(time.Hour))
+	clientID := "test-client"
+
+	rl.Reserve(clientID)
+	r := rl.Reserve(clientID)
+
+	if r.OK() {
+		t.Error("Expected reservation to be denied")
+	}
+	if delay := r.Delay(); delay < 59*time.Minute || delay > time.Hour {
+		t.Errorf("Expected delay close to an hour, got %v", delay)
+	}
+}
+
+func TestReservation_Cancel(t *testing.T) {
+	algorithms := []Algorithm{FixedWindow, TokenBucket, SlidingWindow, SlidingLog, GCRA, LeakyBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			rl := New(
+				WithAlgorithm(algorithm),
+				WithMaxRequests(2),
+				WithWindowDuration(time.Hour),
+			)
+			clientID := "test-client"
+
+			rl.Reserve(clientID)
+			r := rl.Reserve(clientID)
+			if !r.OK() {
+				t.Fatal("Expected second reservation to be granted")
+			}
+
+			r.Cancel()
+			r.Cancel()
+
+			if result := rl.Allow(clientID); !result.Allowed {
+				t.Error("Expected canceled unit to be available again")
+			}
+			if result := rl.Allow(clientID); result.Allowed {
+				t.Error("Expected double cancel to return the unit only once")
+			}
+		})
+	}
+}
+
+func TestWait_Immediate(t *testing.T) {
+	rl := New(WithMaxRequests(1))
+
+	if err := rl.Wait(context.Background(), "test-client"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestWait_BlocksUntilPermitted(t *testing.T) {
+	rl := New(WithAlgorithm(GCRA), WithRefillRate(20), WithBurst(1))
+	clientID := "test-client"
+
+	rl.Allow(clientID)
+
+	start := time.Now()
+	if err := rl.Wait(context.Background(), clientID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected Wait to block, returned after %v", elapsed)
+	}
+}
+
+func TestWait_ExceedsDeadline(t *testing.T) {
+	rl := New(WithMaxRequests(1), WithBlockDuration(time.Minute))
+	clientID := "test-client"
+
+	rl.Allow(clientID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := rl.Wait(ctx, clientID)
+	if !errors.Is(err, ErrWaitExceedsDeadline) {
+		t.Errorf("Expected ErrWaitExceedsDeadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Expected Wait to fail fast, took %v", elapsed)
+	}
+}
+
+func TestWait_ContextCanceled(t *testing.T) {
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	rl := New(WithAlgorithm(LeakyBucket), WithRefillRate(1), WithQueueDepth(5), WithClock(clock))
+	clientID := "test-client"
+
+	rl.Allow(clientID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	if err := rl.Wait(ctx, clientID); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	result := rl.Allow(clientID)
+	if result.Delay != time.Second {
+		t.Errorf("Expected abandoned slot to be returned, next delay is %v", result.Delay)
+	}
+}
+
+func TestWaitN_CostExceedsLimit(t *testing.T) {
+	rl := New(WithAlgorithm(TokenBucket), WithBurst(5))
+
+	if err := rl.WaitN(context.Background(), "test-client", 6); err == nil {
+		t.Error("Expected error when cost exceeds the limit")
+	}
+}
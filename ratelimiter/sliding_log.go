@@ -0,0 +1,67 @@
+package ratelimiter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func (rl *RateLimiter) allowSlidingLog(ctx context.Context, clientID string, now time.Time, n int) (*Result, error) {
+	log, allowed, err := rl.storage.AppendToLog(ctx, clientID, now, rl.windowDuration, rl.maxRequests, n)
+	if err != nil {
+		return rl.storageFailure(ctx, clientID, n, err)
+	}
+	requestsMade := len(log.Timestamps)
+	resetAt := rl.slidingLogResetAt(log, now)
+
+	if !allowed {
+		retryAfter := now.Add(rl.windowDuration)
+		if expire := requestsMade + n - rl.maxRequests - 1; expire >= 0 && expire < requestsMade {
+			retryAfter = log.Timestamps[expire].Add(rl.windowDuration)
+		}
+		retryAfterSec := int(math.Ceil(retryAfter.Sub(now).Seconds()))
+		if retryAfterSec < 1 {
+			retryAfterSec = 1
+		}
+
+		if rl.logOnExceedOnly {
+			rl.logger.Info("Rate limit exceeded",
+				slog.String("client_id", clientID),
+				slog.String("algorithm", rl.algorithm.String()),
+				slog.Int("requests_made", requestsMade),
+				slog.Int("limit", rl.maxRequests),
+				slog.Time("retry_after", retryAfter),
+			)
+		}
+
+		return &Result{
+			Allowed:       false,
+			RequestsMade:  requestsMade,
+			Limit:         rl.maxRequests,
+			RetryAfter:    retryAfter,
+			RetryAfterSec: retryAfterSec,
+			ErrorMessage:  rl.errorMessage,
+			Remaining:     max(0, rl.maxRequests-requestsMade),
+			ResetAt:       resetAt,
+		}, nil
+	}
+
+	return &Result{
+		Allowed:      true,
+		RequestsMade: requestsMade,
+		Limit:        rl.maxRequests,
+		Remaining:    rl.maxRequests - requestsMade,
+		ResetAt:      resetAt,
+	}, nil
+}
+
+// slidingLogResetAt returns when the newest logged request expires.
+func (rl *RateLimiter) slidingLogResetAt(log *storage.RequestLog, now time.Time) time.Time {
+	if len(log.Timestamps) == 0 {
+		return now
+	}
+	return log.Timestamps[len(log.Timestamps)-1].Add(rl.windowDuration)
+}
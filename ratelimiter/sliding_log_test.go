@@ -0,0 +1,65 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter/ratelimitertest"
+)
+
+func TestAllowSlidingLog_ExceedLimit(t *testing.T) {
+	rl := New(
+		WithAlgorithm(SlidingLog),
+		WithMaxRequests(3),
+		WithWindowDuration(time.Hour),
+	)
+	clientID := "test-client"
+
+	first := time.Now()
+	for i := 1; i <= 3; i++ {
+		result := rl.Allow(clientID)
+		if !result.Allowed {
+			t.Errorf("Request %d should be allowed", i)
+		}
+		if result.Remaining != 3-i {
+			t.Errorf("Expected Remaining %d, got %d", 3-i, result.Remaining)
+		}
+	}
+
+	result := rl.Allow(clientID)
+	if result.Allowed {
+		t.Error("Request exceeding limit should not be allowed")
+	}
+	if result.RequestsMade != 3 {
+		t.Errorf("Expected RequestsMade 3, got %d", result.RequestsMade)
+	}
+
+	diff := result.RetryAfter.Sub(first.Add(time.Hour)).Abs()
+	if diff > time.Second {
+		t.Errorf("Expected RetryAfter to be when the oldest entry expires, off by %v", diff)
+	}
+}
+
+func TestAllowSlidingLog_WindowSlides(t *testing.T) {
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	rl := New(
+		WithAlgorithm(SlidingLog),
+		WithMaxRequests(2),
+		WithWindowDuration(100*time.Millisecond),
+		WithClock(clock),
+	)
+	clientID := "test-client"
+
+	rl.Allow(clientID)
+	rl.Allow(clientID)
+
+	if result := rl.Allow(clientID); result.Allowed {
+		t.Error("Request exceeding limit should not be allowed")
+	}
+
+	clock.Advance(100 * time.Millisecond)
+
+	if result := rl.Allow(clientID); !result.Allowed {
+		t.Error("Request should be allowed after the logged entries expire")
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimiter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func (rl *RateLimiter) allowSlidingWindow(ctx context.Context, clientID string, now time.Time, n int) (*Result, error) {
+	counter, allowed, err := rl.storage.SlidingWindowIncrement(ctx, clientID, now, rl.windowDuration, rl.maxRequests, n)
+	if err != nil {
+		return rl.storageFailure(ctx, clientID, n, err)
+	}
+	estimate := counter.Estimate(now, rl.windowDuration)
+	requestsMade := int(math.Ceil(estimate))
+	remaining := max(0, int(math.Floor(float64(rl.maxRequests)-estimate)))
+	resetAt := rl.slidingWindowResetAt(counter, now)
+
+	if !allowed {
+		retryAfter := rl.slidingWindowRetryAfter(counter, now, n)
+		retryAfterSec := int(math.Ceil(retryAfter.Sub(now).Seconds()))
+		if retryAfterSec < 1 {
+			retryAfterSec = 1
+		}
+
+		if rl.logOnExceedOnly {
+			rl.logger.Info("Rate limit exceeded",
+				slog.String("client_id", clientID),
+				slog.String("algorithm", rl.algorithm.String()),
+				slog.Int("requests_made", requestsMade),
+				slog.Int("limit", rl.maxRequests),
+				slog.Time("retry_after", retryAfter),
+			)
+		}
+
+		return &Result{
+			Allowed:       false,
+			RequestsMade:  requestsMade,
+			Limit:         rl.maxRequests,
+			RetryAfter:    retryAfter,
+			RetryAfterSec: retryAfterSec,
+			ErrorMessage:  rl.errorMessage,
+			Remaining:     remaining,
+			ResetAt:       resetAt,
+		}, nil
+	}
+
+	return &Result{
+		Allowed:      true,
+		RequestsMade: requestsMade,
+		Limit:        rl.maxRequests,
+		Remaining:    remaining,
+		ResetAt:      resetAt,
+	}, nil
+}
+
+// slidingWindowResetAt returns when every counted request will have slid out of
+// the window.
+func (rl *RateLimiter) slidingWindowResetAt(counter *storage.WindowCounter, now time.Time) time.Time {
+	switch {
+	case counter.CurrentCount > 0:
+		return counter.WindowStart.Add(2 * rl.windowDuration)
+	case counter.PreviousCount > 0:
+		return counter.WindowStart.Add(rl.windowDuration)
+	default:
+		return now
+	}
+}
+
+// slidingWindowRetryAfter returns the earliest time at which the weighted
+// estimate leaves room for n more units, assuming no further traffic.
+func (rl *RateLimiter) slidingWindowRetryAfter(counter *storage.WindowCounter, now time.Time, n int) time.Time {
+	window := float64(rl.windowDuration)
+	room := float64(rl.maxRequests - n)
+
+	var retryAfter time.Time
+	if float64(counter.CurrentCount) <= room && counter.PreviousCount > 0 {
+		fraction := 1 - (room-float64(counter.CurrentCount))/float64(counter.PreviousCount)
+		retryAfter = counter.WindowStart.Add(time.Duration(math.Ceil(fraction * window)))
+	} else {
+		fraction := 1.0
+		if counter.CurrentCount > 0 {
+			fraction = math.Max(0, 1-room/float64(counter.CurrentCount))
+		}
+		retryAfter = counter.WindowStart.Add(rl.windowDuration).Add(time.Duration(math.Ceil(fraction * window)))
+	}
+
+	if retryAfter.Before(now) {
+		return now
+	}
+	return retryAfter
+}
@@ -0,0 +1,55 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func TestAllowSlidingWindow_ExceedLimit(t *testing.T) {
+	rl := New(
+		WithAlgorithm(SlidingWindow),
+		WithMaxRequests(3),
+		WithWindowDuration(time.Hour),
+	)
+	clientID := "test-client"
+
+	for i := 1; i <= 3; i++ {
+		result := rl.Allow(clientID)
+		if !result.Allowed {
+			t.Errorf("Request %d should be allowed", i)
+		}
+		if result.Remaining != 3-i {
+			t.Errorf("Expected Remaining %d, got %d", 3-i, result.Remaining)
+		}
+	}
+
+	result := rl.Allow(clientID)
+	if result.Allowed {
+		t.Error("Request exceeding limit should not be allowed")
+	}
+	if result.RetryAfterSec < 1 {
+		t.Errorf("Expected RetryAfterSec to be positive, got %d", result.RetryAfterSec)
+	}
+	if !result.RetryAfter.After(time.Now()) {
+		t.Error("Expected RetryAfter to be in the future")
+	}
+}
+
+func TestSlidingWindowRetryAfter(t *testing.T) {
+	rl := New(WithAlgorithm(SlidingWindow), WithMaxRequests(10), WithWindowDuration(time.Minute))
+	windowStart := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	counter := &storage.WindowCounter{PreviousCount: 10, CurrentCount: 0, WindowStart: windowStart}
+	retryAfter := rl.slidingWindowRetryAfter(counter, windowStart, 1)
+	if expected := windowStart.Add(6 * time.Second); !retryAfter.Equal(expected) {
+		t.Errorf("Expected RetryAfter %v, got %v", expected, retryAfter)
+	}
+
+	counter = &storage.WindowCounter{PreviousCount: 0, CurrentCount: 10, WindowStart: windowStart}
+	retryAfter = rl.slidingWindowRetryAfter(counter, windowStart.Add(30*time.Second), 1)
+	if expected := windowStart.Add(66 * time.Second); !retryAfter.Equal(expected) {
+		t.Errorf("Expected RetryAfter %v, got %v", expected, retryAfter)
+	}
+}
@@ -0,0 +1,204 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+type Status struct {
+	Limit        int
+	Remaining    int
+	ResetAt      time.Time
+	Blocked      bool
+	BlockedUntil time.Time
+}
+
+// Status reports the client's current quota without consuming any of it. The
+// fixed window is read through GetClientData; other algorithms are probed with a
+// zero-cost check, which storages treat as a read: it creates no state for
+// unknown clients and does not count as a use for eviction.
+func (rl *RateLimiter) Status(clientID string) *Status {
+	status, _ := rl.StatusContext(context.Background(), clientID)
+	return status
+}
+
+// StatusContext is like Status but passes ctx to the storage. If the storage
+// fails, the error is returned with the fallback storage's status, or a status
+// reflecting the failure policy.
+func (rl *RateLimiter) StatusContext(ctx context.Context, clientID string) (*Status, error) {
+	now := rl.clock.Now()
+
+	var status *Status
+	var err error
+	switch rl.algorithm {
+	case TokenBucket:
+		status, err = rl.statusTokenBucket(ctx, clientID, now)
+	case SlidingWindow:
+		status, err = rl.statusSlidingWindow(ctx, clientID, now)
+	case SlidingLog:
+		status, err = rl.statusSlidingLog(ctx, clientID, now)
+	case GCRA:
+		status, err = rl.statusGCRA(ctx, clientID, now)
+	case LeakyBucket:
+		status, err = rl.statusLeakyBucket(ctx, clientID, now)
+	default:
+		status, err = rl.statusFixedWindow(ctx, clientID, now)
+	}
+
+	if err != nil {
+		rl.logStorageError(clientID, err)
+		err = fmt.Errorf("ratelimiter: storage: %w", err)
+
+		if rl.fallback != nil {
+			status, _ = rl.fallback.StatusContext(ctx, clientID)
+			return status, err
+		}
+
+		status = &Status{
+			Limit:     rl.limit(),
+			Remaining: rl.limit(),
+			ResetAt:   now,
+		}
+		if rl.failurePolicy == FailClosed {
+			status.Remaining = 0
+		}
+		return status, err
+	}
+	return status, nil
+}
+
+func (rl *RateLimiter) statusFixedWindow(ctx context.Context, clientID string, now time.Time) (*Status, error) {
+	status := &Status{
+		Limit:     rl.maxRequests,
+		Remaining: rl.maxRequests,
+		ResetAt:   now,
+	}
+
+	data, exists, err := rl.storage.GetClientData(ctx, clientID)
+	if err != nil || !exists {
+		return status, err
+	}
+
+	if data.BlockedUntil.After(now) {
+		status.Remaining = 0
+		status.ResetAt = data.BlockedUntil
+		status.Blocked = true
+		status.BlockedUntil = data.BlockedUntil
+		return status, nil
+	}
+
+	if now.Sub(data.WindowStart) < rl.windowDuration && data.BlockedUntil.IsZero() {
+		status.Remaining = max(0, rl.maxRequests-data.RequestCount)
+		status.ResetAt = data.WindowStart.Add(rl.windowDuration)
+	}
+
+	return status, nil
+}
+
+func (rl *RateLimiter) statusTokenBucket(ctx context.Context, clientID string, now time.Time) (*Status, error) {
+	bucket, _, err := rl.storage.TakeToken(ctx, clientID, now, rl.refillRate, rl.burst, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Limit:     rl.burst,
+		Remaining: int(math.Floor(bucket.Tokens)),
+		ResetAt:   rl.tokenBucketResetAt(bucket, now),
+	}
+	if rl.refillRate > 0 {
+		if bucket.Tokens < 1 {
+			status.Blocked = true
+			status.BlockedUntil = now.Add(time.Duration((1 - bucket.Tokens) / rl.refillRate * float64(time.Second)))
+		}
+	}
+	return status, nil
+}
+
+func (rl *RateLimiter) statusSlidingWindow(ctx context.Context, clientID string, now time.Time) (*Status, error) {
+	counter, _, err := rl.storage.SlidingWindowIncrement(ctx, clientID, now, rl.windowDuration, rl.maxRequests, 0)
+	if err != nil {
+		return nil, err
+	}
+	estimate := counter.Estimate(now, rl.windowDuration)
+
+	status := &Status{
+		Limit:     rl.maxRequests,
+		Remaining: max(0, int(math.Floor(float64(rl.maxRequests)-estimate))),
+		ResetAt:   rl.slidingWindowResetAt(counter, now),
+	}
+	if estimate+1 > float64(rl.maxRequests) {
+		status.Blocked = true
+		status.BlockedUntil = rl.slidingWindowRetryAfter(counter, now, 1)
+	}
+	return status, nil
+}
+
+func (rl *RateLimiter) statusSlidingLog(ctx context.Context, clientID string, now time.Time) (*Status, error) {
+	log, _, err := rl.storage.AppendToLog(ctx, clientID, now, rl.windowDuration, rl.maxRequests, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Limit:     rl.maxRequests,
+		Remaining: max(0, rl.maxRequests-len(log.Timestamps)),
+		ResetAt:   rl.slidingLogResetAt(log, now),
+	}
+	if status.Remaining == 0 {
+		status.Blocked = true
+		status.BlockedUntil = now.Add(rl.windowDuration)
+		if oldest := log.Oldest(); !oldest.IsZero() {
+			status.BlockedUntil = oldest.Add(rl.windowDuration)
+		}
+	}
+	return status, nil
+}
+
+func (rl *RateLimiter) statusGCRA(ctx context.Context, clientID string, now time.Time) (*Status, error) {
+	interval := rl.emissionInterval()
+	tat, _, err := rl.storage.UpdateArrivalTime(ctx, clientID, now, interval, rl.burst, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Limit:   rl.burst,
+		ResetAt: tat,
+	}
+	if interval > 0 {
+		status.Remaining = max(0, int((interval*time.Duration(rl.burst)-tat.Sub(now))/interval))
+	}
+	if status.Remaining == 0 {
+		status.Blocked = true
+		status.BlockedUntil = tat.Add(interval * time.Duration(1-rl.burst))
+	}
+	return status, nil
+}
+
+func (rl *RateLimiter) statusLeakyBucket(ctx context.Context, clientID string, now time.Time) (*Status, error) {
+	interval := rl.emissionInterval()
+	maxDelay := rl.leakyBucketMaxDelay(interval)
+	slot, _, err := rl.storage.ScheduleLeak(ctx, clientID, now, interval, maxDelay, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := 0
+	if interval > 0 {
+		queued = int(math.Ceil(float64(slot.Sub(now)) / float64(interval)))
+	}
+
+	status := &Status{
+		Limit:     rl.queueDepth,
+		Remaining: max(0, rl.queueDepth-queued),
+		ResetAt:   slot,
+	}
+	if slot.Sub(now) > maxDelay {
+		status.Blocked = true
+		status.BlockedUntil = slot.Add(-maxDelay)
+	}
+	return status, nil
+}
@@ -0,0 +1,133 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func TestStatus_UnknownClient(t *testing.T) {
+	rl := New(WithMaxRequests(10))
+
+	status := rl.Status("unknown")
+	if status.Limit != 10 {
+		t.Errorf("Expected Limit 10, got %d", status.Limit)
+	}
+	if status.Remaining != 10 {
+		t.Errorf("Expected Remaining 10, got %d", status.Remaining)
+	}
+	if status.Blocked {
+		t.Error("Expected unknown client not to be blocked")
+	}
+}
+
+func TestStatus_FixedWindow(t *testing.T) {
+	rl := New(WithMaxRequests(3), WithWindowDuration(time.Minute), WithBlockDuration(time.Hour))
+	clientID := "test-client"
+
+	start := time.Now()
+	rl.Allow(clientID)
+	rl.Allow(clientID)
+
+	status := rl.Status(clientID)
+	if status.Remaining != 1 {
+		t.Errorf("Expected Remaining 1, got %d", status.Remaining)
+	}
+	if diff := status.ResetAt.Sub(start.Add(time.Minute)).Abs(); diff > time.Second {
+		t.Errorf("Expected ResetAt at the end of the window, off by %v", diff)
+	}
+
+	if status := rl.Status(clientID); status.Remaining != 1 {
+		t.Errorf("Status should not consume quota, got Remaining %d", status.Remaining)
+	}
+
+	rl.Allow(clientID)
+	rl.Allow(clientID)
+
+	status = rl.Status(clientID)
+	if !status.Blocked {
+		t.Error("Expected client to be blocked")
+	}
+	if status.Remaining != 0 {
+		t.Errorf("Expected Remaining 0, got %d", status.Remaining)
+	}
+	if diff := status.BlockedUntil.Sub(start.Add(time.Hour)).Abs(); diff > time.Second {
+		t.Errorf("Expected BlockedUntil an hour away, off by %v", diff)
+	}
+}
+
+func TestStatus_DoesNotConsume(t *testing.T) {
+	algorithms := []Algorithm{TokenBucket, SlidingWindow, SlidingLog, GCRA, LeakyBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			rl := New(
+				WithAlgorithm(algorithm),
+				WithMaxRequests(3),
+				WithWindowDuration(time.Hour),
+			)
+			clientID := "test-client"
+
+			rl.Allow(clientID)
+
+			for i := 0; i < 5; i++ {
+				status := rl.Status(clientID)
+				if status.Remaining != 2 {
+					t.Errorf("Expected Remaining 2, got %d", status.Remaining)
+				}
+				if status.Blocked {
+					t.Error("Expected client not to be blocked")
+				}
+			}
+
+			rl.Allow(clientID)
+			rl.Allow(clientID)
+
+			status := rl.Status(clientID)
+			if !status.Blocked {
+				t.Error("Expected client to be blocked once the quota is used")
+			}
+			if !status.BlockedUntil.After(time.Now()) {
+				t.Error("Expected BlockedUntil to be in the future")
+			}
+			if status.Limit != 3 {
+				t.Errorf("Expected Limit 3, got %d", status.Limit)
+			}
+		})
+	}
+}
+
+func TestStatus_UnknownClientLeavesStorageUntouched(t *testing.T) {
+	algorithms := []Algorithm{FixedWindow, TokenBucket, SlidingWindow, SlidingLog, GCRA, LeakyBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			store := storage.NewBoundedMemoryStorage(1, storage.EvictLeastRecentlyUsed)
+			rl := New(
+				WithStorage(store),
+				WithAlgorithm(algorithm),
+				WithMaxRequests(1),
+				WithWindowDuration(time.Hour),
+				WithLogger(quietLogger),
+			)
+
+			rl.Allow("limited")
+			if rl.Allow("limited").Allowed {
+				t.Fatal("Expected the client to be limited")
+			}
+
+			for i := 0; i < 10; i++ {
+				rl.Status(fmt.Sprintf("probe-%d", i))
+			}
+
+			if store.Evictions() != 0 {
+				t.Errorf("Expected Status not to evict anyone, got %d evictions", store.Evictions())
+			}
+			if rl.Allow("limited").Allowed {
+				t.Error("Expected the limited client to stay limited")
+			}
+		})
+	}
+}
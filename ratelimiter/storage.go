@@ -0,0 +1,156 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+// StorageV2 is the storage interface used by RateLimiter. Unlike Storage, every
+// method takes a context and reports failures, so backends that talk to the
+// network can honour deadlines and let the limiter see when they are unavailable.
+type StorageV2 interface {
+	GetClientData(ctx context.Context, clientID string) (*storage.ClientData, bool, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	Clear(ctx context.Context) error
+	CheckAndIncrementN(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*storage.ClientData, bool, error)
+	TakeToken(ctx context.Context, clientID string, now time.Time, refillRate float64, capacity int, n int) (*storage.TokenBucket, bool, error)
+	SlidingWindowIncrement(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.WindowCounter, bool, error)
+	AppendToLog(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.RequestLog, bool, error)
+	UpdateArrivalTime(ctx context.Context, clientID string, now time.Time, emissionInterval time.Duration, burst int, n int) (time.Time, bool, error)
+	ScheduleLeak(ctx context.Context, clientID string, now time.Time, leakInterval time.Duration, maxDelay time.Duration, n int) (time.Time, bool, error)
+}
+
+// ErrUnsupported is returned by adapted storages for algorithms whose optional
+// interface the wrapped storage does not implement.
+var ErrUnsupported = errors.New("ratelimiter: operation not supported by storage")
+
+// AdaptStorage wraps a Storage, such as storage.MemoryStorage, so that it
+// satisfies StorageV2. The wrapped storage never returns errors, except for
+// operations that need an optional interface it does not implement: a cost
+// other than 1 without WeightedStorage (ErrUnsupportedCost), or an algorithm
+// without its interface (ErrUnsupported).
+func AdaptStorage(s Storage) StorageV2 {
+	return &storageAdapter{storage: s}
+}
+
+type storageAdapter struct {
+	storage Storage
+}
+
+// supports reports whether s can run algorithm. Only adapted storages can lack
+// an algorithm; StorageV2 implementations have every method.
+func supports(s StorageV2, algorithm Algorithm) bool {
+	a, ok := s.(*storageAdapter)
+	if !ok {
+		return true
+	}
+
+	switch algorithm {
+	case TokenBucket:
+		_, ok = a.storage.(TokenBucketStorage)
+	case SlidingWindow:
+		_, ok = a.storage.(SlidingWindowStorage)
+	case SlidingLog:
+		_, ok = a.storage.(SlidingLogStorage)
+	case GCRA:
+		_, ok = a.storage.(GCRAStorage)
+	case LeakyBucket:
+		_, ok = a.storage.(LeakyBucketStorage)
+	}
+	return ok
+}
+
+// supportsCost reports whether s can charge costs other than 1 under
+// algorithm. Only the fixed window on an adapted storage without
+// WeightedStorage cannot.
+func supportsCost(s StorageV2, algorithm Algorithm) bool {
+	a, ok := s.(*storageAdapter)
+	if !ok || algorithm != FixedWindow {
+		return true
+	}
+	_, ok = a.storage.(WeightedStorage)
+	return ok
+}
+
+// unwrap returns the Storage behind an adapted storage, for error messages.
+func unwrap(s StorageV2) any {
+	if a, ok := s.(*storageAdapter); ok {
+		return a.storage
+	}
+	return s
+}
+
+func (a *storageAdapter) GetClientData(_ context.Context, clientID string) (*storage.ClientData, bool, error) {
+	data, exists := a.storage.GetClientData(clientID)
+	return data, exists, nil
+}
+
+func (a *storageAdapter) DeleteClient(_ context.Context, clientID string) error {
+	a.storage.DeleteClient(clientID)
+	return nil
+}
+
+func (a *storageAdapter) Clear(_ context.Context) error {
+	a.storage.Clear()
+	return nil
+}
+
+func (a *storageAdapter) CheckAndIncrementN(_ context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*storage.ClientData, bool, error) {
+	if s, ok := a.storage.(WeightedStorage); ok {
+		data, allowed := s.CheckAndIncrementN(clientID, now, windowDuration, maxRequests, blockDuration, n)
+		return data, allowed, nil
+	}
+	if n != 1 {
+		return nil, false, ErrUnsupportedCost
+	}
+	data, allowed := a.storage.CheckAndIncrement(clientID, now, windowDuration, maxRequests, blockDuration)
+	return data, allowed, nil
+}
+
+func (a *storageAdapter) TakeToken(_ context.Context, clientID string, now time.Time, refillRate float64, capacity int, n int) (*storage.TokenBucket, bool, error) {
+	s, ok := a.storage.(TokenBucketStorage)
+	if !ok {
+		return nil, false, ErrUnsupported
+	}
+	bucket, allowed := s.TakeToken(clientID, now, refillRate, capacity, n)
+	return bucket, allowed, nil
+}
+
+func (a *storageAdapter) SlidingWindowIncrement(_ context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.WindowCounter, bool, error) {
+	s, ok := a.storage.(SlidingWindowStorage)
+	if !ok {
+		return nil, false, ErrUnsupported
+	}
+	counter, allowed := s.SlidingWindowIncrement(clientID, now, windowDuration, maxRequests, n)
+	return counter, allowed, nil
+}
+
+func (a *storageAdapter) AppendToLog(_ context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.RequestLog, bool, error) {
+	s, ok := a.storage.(SlidingLogStorage)
+	if !ok {
+		return nil, false, ErrUnsupported
+	}
+	log, allowed := s.AppendToLog(clientID, now, windowDuration, maxRequests, n)
+	return log, allowed, nil
+}
+
+func (a *storageAdapter) UpdateArrivalTime(_ context.Context, clientID string, now time.Time, emissionInterval time.Duration, burst int, n int) (time.Time, bool, error) {
+	s, ok := a.storage.(GCRAStorage)
+	if !ok {
+		return time.Time{}, false, ErrUnsupported
+	}
+	tat, allowed := s.UpdateArrivalTime(clientID, now, emissionInterval, burst, n)
+	return tat, allowed, nil
+}
+
+func (a *storageAdapter) ScheduleLeak(_ context.Context, clientID string, now time.Time, leakInterval time.Duration, maxDelay time.Duration, n int) (time.Time, bool, error) {
+	s, ok := a.storage.(LeakyBucketStorage)
+	if !ok {
+		return time.Time{}, false, ErrUnsupported
+	}
+	slot, allowed := s.ScheduleLeak(clientID, now, leakInterval, maxDelay, n)
+	return slot, allowed, nil
+}
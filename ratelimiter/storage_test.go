@@ -0,0 +1,264 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+var errUnavailable = errors.New("storage unavailable")
+
+// failingStorage fails every call with err, or with the context's error once
+// the context is done.
+type failingStorage struct {
+	err error
+}
+
+func (f *failingStorage) fail(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.err
+}
+
+func (f *failingStorage) GetClientData(ctx context.Context, clientID string) (*storage.ClientData, bool, error) {
+	return nil, false, f.fail(ctx)
+}
+
+func (f *failingStorage) DeleteClient(ctx context.Context, clientID string) error {
+	return f.fail(ctx)
+}
+
+func (f *failingStorage) Clear(ctx context.Context) error {
+	return f.fail(ctx)
+}
+
+func (f *failingStorage) CheckAndIncrementN(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*storage.ClientData, bool, error) {
+	return nil, false, f.fail(ctx)
+}
+
+func (f *failingStorage) TakeToken(ctx context.Context, clientID string, now time.Time, refillRate float64, capacity int, n int) (*storage.TokenBucket, bool, error) {
+	return nil, false, f.fail(ctx)
+}
+
+func (f *failingStorage) SlidingWindowIncrement(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.WindowCounter, bool, error) {
+	return nil, false, f.fail(ctx)
+}
+
+func (f *failingStorage) AppendToLog(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.RequestLog, bool, error) {
+	return nil, false, f.fail(ctx)
+}
+
+func (f *failingStorage) UpdateArrivalTime(ctx context.Context, clientID string, now time.Time, emissionInterval time.Duration, burst int, n int) (time.Time, bool, error) {
+	return time.Time{}, false, f.fail(ctx)
+}
+
+func (f *failingStorage) ScheduleLeak(ctx context.Context, clientID string, now time.Time, leakInterval time.Duration, maxDelay time.Duration, n int) (time.Time, bool, error) {
+	return time.Time{}, false, f.fail(ctx)
+}
+
+var quietLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestAdaptStorage(t *testing.T) {
+	memory := storage.NewMemoryStorage()
+	adapted := AdaptStorage(memory)
+	ctx := context.Background()
+	now := time.Now()
+
+	data, allowed, err := adapted.CheckAndIncrementN(ctx, "client", now, time.Minute, 5, time.Minute, 2)
+	if err != nil || !allowed || data.RequestCount != 2 {
+		t.Fatalf("Expected 2 requests allowed, got %+v/%v/%v", data, allowed, err)
+	}
+
+	if _, exists := memory.GetClientData("client"); !exists {
+		t.Error("Expected adapter to write through to the wrapped storage")
+	}
+
+	if err := adapted.DeleteClient(ctx, "client"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists, _ := adapted.GetClientData(ctx, "client"); exists {
+		t.Error("Expected client to be deleted")
+	}
+}
+
+var (
+	_ WeightedStorage      = (*storage.MemoryStorage)(nil)
+	_ TokenBucketStorage   = (*storage.MemoryStorage)(nil)
+	_ SlidingWindowStorage = (*storage.MemoryStorage)(nil)
+	_ SlidingLogStorage    = (*storage.MemoryStorage)(nil)
+	_ GCRAStorage          = (*storage.MemoryStorage)(nil)
+	_ LeakyBucketStorage   = (*storage.MemoryStorage)(nil)
+	_ WeightedStorage      = (*storage.ShardedMemoryStorage)(nil)
+	_ TokenBucketStorage   = (*storage.ShardedMemoryStorage)(nil)
+	_ SlidingWindowStorage = (*storage.ShardedMemoryStorage)(nil)
+	_ SlidingLogStorage    = (*storage.ShardedMemoryStorage)(nil)
+	_ GCRAStorage          = (*storage.ShardedMemoryStorage)(nil)
+	_ LeakyBucketStorage   = (*storage.ShardedMemoryStorage)(nil)
+)
+
+// plainStorage hides every method of the wrapped storage except those of
+// Storage, like a backend written before the optional interfaces existed.
+type plainStorage struct {
+	Storage
+}
+
+func TestAdaptStorage_PlainStorage(t *testing.T) {
+	rl := New(WithStorage(plainStorage{storage.NewMemoryStorage()}), WithMaxRequests(2), WithLogger(quietLogger))
+
+	for i := 0; i < 2; i++ {
+		if !rl.Allow("client").Allowed {
+			t.Fatalf("Request %d: expected allowed", i+1)
+		}
+	}
+	if rl.Allow("client").Allowed {
+		t.Error("Expected the third request to be denied")
+	}
+
+	if _, err := rl.AllowNContext(context.Background(), "other", 2); !errors.Is(err, ErrUnsupportedCost) {
+		t.Errorf("Expected a cost of 2 to be unsupported, got %v", err)
+	}
+}
+
+func TestAdaptStorage_PlainStorageDeniesUnsupportedCost(t *testing.T) {
+	policies := []FailurePolicy{FailOpen, FailClosed}
+
+	for _, policy := range policies {
+		t.Run(policy.String(), func(t *testing.T) {
+			rl := New(
+				WithStorage(plainStorage{storage.NewMemoryStorage()}),
+				WithMaxRequests(2),
+				WithFailurePolicy(policy),
+				WithLogger(quietLogger),
+			)
+
+			for i := 0; i < 50; i++ {
+				result, err := rl.AllowNContext(context.Background(), "client", 5)
+				if !errors.Is(err, ErrUnsupportedCost) {
+					t.Fatalf("Request %d: expected ErrUnsupportedCost, got %v", i+1, err)
+				}
+				if result.Allowed || result.Degraded {
+					t.Fatalf("Request %d: expected a denial outside the failure policy, got %+v", i+1, result)
+				}
+			}
+
+			if err := rl.WaitN(context.Background(), "client", 5); !errors.Is(err, ErrUnsupportedCost) {
+				t.Errorf("Expected WaitN to fail with ErrUnsupportedCost, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAdaptStorage_PlainFallbackDeniesUnsupportedCost(t *testing.T) {
+	rl := New(
+		WithStorageV2(&failingStorage{err: errUnavailable}),
+		WithFallbackStorage(plainStorage{storage.NewMemoryStorage()}),
+		WithLogger(quietLogger),
+	)
+
+	result, err := rl.AllowNContext(context.Background(), "client", 2)
+	if !errors.Is(err, ErrUnsupportedCost) || result.Allowed {
+		t.Errorf("Expected the fallback to deny with ErrUnsupportedCost, got %v/%v", result.Allowed, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := rl.WaitN(ctx, "client", 2); !errors.Is(err, ErrUnsupportedCost) {
+		t.Errorf("Expected WaitN to fail with ErrUnsupportedCost, got %v", err)
+	}
+}
+
+func TestNew_UnsupportedAlgorithm(t *testing.T) {
+	algorithms := []Algorithm{TokenBucket, SlidingWindow, SlidingLog, GCRA, LeakyBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected New to panic")
+				}
+			}()
+			New(WithStorage(plainStorage{storage.NewMemoryStorage()}), WithAlgorithm(algorithm))
+		})
+	}
+}
+
+func TestNew_UnsupportedFallbackAlgorithm(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected New to panic")
+		}
+	}()
+	New(WithFallbackStorage(plainStorage{storage.NewMemoryStorage()}), WithAlgorithm(GCRA))
+}
+
+func TestAllowNContext_StorageError(t *testing.T) {
+	algorithms := []Algorithm{FixedWindow, TokenBucket, SlidingWindow, SlidingLog, GCRA, LeakyBucket}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			rl := New(
+				WithStorageV2(&failingStorage{err: errUnavailable}),
+				WithAlgorithm(algorithm),
+				WithMaxRequests(5),
+				WithLogger(quietLogger),
+			)
+
+			result, err := rl.AllowNContext(context.Background(), "client", 1)
+			if !errors.Is(err, errUnavailable) {
+				t.Errorf("Expected storage error, got %v", err)
+			}
+			if result == nil || !result.Allowed {
+				t.Errorf("Expected request to be allowed on storage error, got %+v", result)
+			}
+			if result.Limit != 5 {
+				t.Errorf("Expected Limit 5, got %d", result.Limit)
+			}
+
+			if !rl.Allow("client").Allowed {
+				t.Error("Expected Allow to let the request through")
+			}
+		})
+	}
+}
+
+func TestAllowNContext_PassesContext(t *testing.T) {
+	rl := New(WithStorageV2(&failingStorage{}), WithLogger(quietLogger))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := rl.AllowContext(ctx, "client"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWaitN_StorageError(t *testing.T) {
+	rl := New(WithStorageV2(&failingStorage{err: errUnavailable}), WithLogger(quietLogger))
+
+	if err := rl.Wait(context.Background(), "client"); !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected storage error, got %v", err)
+	}
+}
+
+func TestStatusContext_StorageError(t *testing.T) {
+	rl := New(
+		WithStorageV2(&failingStorage{err: errUnavailable}),
+		WithAlgorithm(TokenBucket),
+		WithBurst(7),
+		WithLogger(quietLogger),
+	)
+
+	status, err := rl.StatusContext(context.Background(), "client")
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected storage error, got %v", err)
+	}
+	if status.Limit != 7 || status.Remaining != 7 {
+		t.Errorf("Expected full quota of 7, got %d/%d", status.Remaining, status.Limit)
+	}
+}
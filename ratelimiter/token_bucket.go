@@ -0,0 +1,67 @@
+package ratelimiter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+func (rl *RateLimiter) allowTokenBucket(ctx context.Context, clientID string, now time.Time, n int) (*Result, error) {
+	bucket, allowed, err := rl.storage.TakeToken(ctx, clientID, now, rl.refillRate, rl.burst, n)
+	if err != nil {
+		return rl.storageFailure(ctx, clientID, n, err)
+	}
+	remaining := int(math.Floor(bucket.Tokens))
+	resetAt := rl.tokenBucketResetAt(bucket, now)
+
+	if !allowed {
+		wait := rl.blockDuration
+		if rl.refillRate > 0 {
+			wait = time.Duration((float64(n) - bucket.Tokens) / rl.refillRate * float64(time.Second))
+		}
+		retryAfter := now.Add(wait)
+		retryAfterSec := int(math.Ceil(wait.Seconds()))
+		if retryAfterSec < 1 {
+			retryAfterSec = 1
+		}
+
+		if rl.logOnExceedOnly {
+			rl.logger.Info("Rate limit exceeded",
+				slog.String("client_id", clientID),
+				slog.String("algorithm", rl.algorithm.String()),
+				slog.Int("limit", rl.burst),
+				slog.Time("retry_after", retryAfter),
+			)
+		}
+
+		return &Result{
+			Allowed:       false,
+			RequestsMade:  rl.burst - remaining,
+			Limit:         rl.burst,
+			RetryAfter:    retryAfter,
+			RetryAfterSec: retryAfterSec,
+			ErrorMessage:  rl.errorMessage,
+			Remaining:     remaining,
+			ResetAt:       resetAt,
+		}, nil
+	}
+
+	return &Result{
+		Allowed:      true,
+		RequestsMade: rl.burst - remaining,
+		Limit:        rl.burst,
+		Remaining:    remaining,
+		ResetAt:      resetAt,
+	}, nil
+}
+
+// tokenBucketResetAt returns when the bucket will be full again.
+func (rl *RateLimiter) tokenBucketResetAt(bucket *storage.TokenBucket, now time.Time) time.Time {
+	if rl.refillRate <= 0 {
+		return now
+	}
+	return now.Add(time.Duration((float64(rl.burst) - bucket.Tokens) / rl.refillRate * float64(time.Second)))
+}
@@ -0,0 +1,115 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter/ratelimitertest"
+)
+
+func TestNew_TokenBucketDefaults(t *testing.T) {
+	rl := New(
+		WithAlgorithm(TokenBucket),
+		WithMaxRequests(60),
+		WithWindowDuration(time.Minute),
+	)
+
+	if rl.algorithm != TokenBucket {
+		t.Errorf("Expected algorithm TokenBucket, got %v", rl.algorithm)
+	}
+	if rl.refillRate != 1 {
+		t.Errorf("Expected derived refillRate 1, got %v", rl.refillRate)
+	}
+	if rl.burst != 60 {
+		t.Errorf("Expected derived burst 60, got %d", rl.burst)
+	}
+}
+
+func TestAllowTokenBucket_Burst(t *testing.T) {
+	rl := New(
+		WithAlgorithm(TokenBucket),
+		WithBurst(5),
+		WithRefillRate(0.001),
+	)
+	clientID := "test-client"
+
+	for i := 1; i <= 5; i++ {
+		result := rl.Allow(clientID)
+		if !result.Allowed {
+			t.Errorf("Request %d should be allowed within burst", i)
+		}
+		if result.Remaining != 5-i {
+			t.Errorf("Expected Remaining %d, got %d", 5-i, result.Remaining)
+		}
+		if result.Limit != 5 {
+			t.Errorf("Expected Limit 5, got %d", result.Limit)
+		}
+	}
+
+	result := rl.Allow(clientID)
+	if result.Allowed {
+		t.Error("Request exceeding burst should not be allowed")
+	}
+	if result.Remaining != 0 {
+		t.Errorf("Expected Remaining 0, got %d", result.Remaining)
+	}
+	if result.RetryAfterSec < 1 {
+		t.Errorf("Expected RetryAfterSec to be positive, got %d", result.RetryAfterSec)
+	}
+	if result.ErrorMessage != "Rate limit exceeded" {
+		t.Errorf("Expected error message, got %s", result.ErrorMessage)
+	}
+}
+
+func TestAllowTokenBucket_Refill(t *testing.T) {
+	clock := ratelimitertest.NewFakeClock(time.Now())
+	rl := New(
+		WithAlgorithm(TokenBucket),
+		WithBurst(2),
+		WithRefillRate(20),
+		WithClock(clock),
+	)
+	clientID := "test-client"
+
+	rl.Allow(clientID)
+	rl.Allow(clientID)
+
+	result := rl.Allow(clientID)
+	if result.Allowed {
+		t.Error("Bucket should be empty")
+	}
+
+	clock.Advance(100 * time.Millisecond)
+
+	result = rl.Allow(clientID)
+	if !result.Allowed {
+		t.Error("Request should be allowed after refill")
+	}
+}
+
+func TestAllowTokenBucket_ConcurrentRequests(t *testing.T) {
+	rl := New(
+		WithAlgorithm(TokenBucket),
+		WithBurst(100),
+		WithRefillRate(0.001),
+	)
+	clientID := "concurrent-client"
+
+	results := make(chan bool, 150)
+	for i := 0; i < 150; i++ {
+		go func() {
+			results <- rl.Allow(clientID).Allowed
+		}()
+	}
+
+	allowedCount := 0
+	for i := 0; i < 150; i++ {
+		if <-results {
+			allowedCount++
+		}
+	}
+
+	if allowedCount != 100 {
+		t.Errorf("Expected exactly 100 allowed requests, got %d", allowedCount)
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"time"
+)
+
+// UpdateArrivalTime applies the generic cell rate algorithm to the client's
+// theoretical arrival time (TAT). A request is conforming when now is no earlier
+// than TAT + n*emissionInterval - burst*emissionInterval. It returns the stored
+// TAT after the call (advanced by n*emissionInterval if the request was allowed).
+// A negative n moves the TAT back, but never before now. A zero n only reports
+// the TAT without storing it.
+func (s *MemoryStorage) UpdateArrivalTime(clientID string, now time.Time, emissionInterval time.Duration, burst int, n int) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tat, exists := s.arrivals[clientID]
+	if !exists || tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(emissionInterval * time.Duration(n))
+	if n < 0 {
+		if newTAT.Before(now) {
+			newTAT = now
+		}
+		s.arrivals[clientID] = newTAT
+		s.touch(clientID, newTAT)
+		s.use(clientID, now, time.Time{})
+		return newTAT, true
+	}
+	allowAt := newTAT.Add(-emissionInterval * time.Duration(burst))
+	if now.Before(allowAt) {
+		return tat, false
+	}
+	if n == 0 {
+		return tat, true
+	}
+
+	s.arrivals[clientID] = newTAT
+	s.touch(clientID, newTAT)
+	s.use(clientID, now, time.Time{})
+	return newTAT, true
+}
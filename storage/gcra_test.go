@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateArrivalTime_Burst(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		tat, allowed := storage.UpdateArrivalTime("test-client", now, time.Second, 3, 1)
+		if !allowed {
+			t.Errorf("Request %d should be allowed within burst", i)
+		}
+		if expected := now.Add(time.Duration(i) * time.Second); !tat.Equal(expected) {
+			t.Errorf("Expected TAT %v, got %v", expected, tat)
+		}
+	}
+
+	tat, allowed := storage.UpdateArrivalTime("test-client", now, time.Second, 3, 1)
+	if allowed {
+		t.Error("Request exceeding burst should not be allowed")
+	}
+	if expected := now.Add(3 * time.Second); !tat.Equal(expected) {
+		t.Errorf("Denied request should not advance TAT, got %v", tat)
+	}
+}
+
+func TestUpdateArrivalTime_SmoothRate(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	storage.UpdateArrivalTime("test-client", now, time.Second, 1, 1)
+
+	if _, allowed := storage.UpdateArrivalTime("test-client", now.Add(500*time.Millisecond), time.Second, 1, 1); allowed {
+		t.Error("Request before the emission interval should not be allowed")
+	}
+	if _, allowed := storage.UpdateArrivalTime("test-client", now.Add(time.Second), time.Second, 1, 1); !allowed {
+		t.Error("Request after the emission interval should be allowed")
+	}
+}
+
+func TestUpdateArrivalTime_IdleClientResets(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	storage.UpdateArrivalTime("test-client", now, time.Second, 2, 1)
+	storage.UpdateArrivalTime("test-client", now, time.Second, 2, 1)
+
+	later := now.Add(time.Hour)
+	tat, allowed := storage.UpdateArrivalTime("test-client", later, time.Second, 2, 1)
+	if !allowed {
+		t.Error("Idle client should be allowed")
+	}
+	if expected := later.Add(time.Second); !tat.Equal(expected) {
+		t.Errorf("Expected TAT %v, got %v", expected, tat)
+	}
+}
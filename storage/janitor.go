@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+type JanitorStats struct {
+	Sweeps      uint64
+	Evicted     uint64
+	LastSweep   time.Time
+	LastEvicted int
+}
+
+// touch records that clientID holds state until expiresAt. The check methods call
+// it with the moment their state becomes indistinguishable from a new client, so
+// the janitor can evict it without changing any decision. Must be called with
+// s.mu held.
+func (s *MemoryStorage) touch(clientID string, expiresAt time.Time) {
+	if current, exists := s.expiries[clientID]; !exists || expiresAt.After(current) {
+		s.expiries[clientID] = expiresAt
+	}
+}
+
+func (s *MemoryStorage) deleteLocked(clientID string) {
+	delete(s.clients, clientID)
+	delete(s.buckets, clientID)
+	delete(s.windows, clientID)
+	delete(s.logs, clientID)
+	delete(s.arrivals, clientID)
+	delete(s.queues, clientID)
+	delete(s.expiries, clientID)
+	s.forget(clientID)
+}
+
+// Sweep evicts every client whose window and block have expired as of now and
+// returns how many were evicted. Clients written only through SetClientData,
+// IncrementRequestCount, ResetWindow or BlockClient carry no expiry and are kept.
+func (s *MemoryStorage) Sweep(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	evicted := 0
+	for clientID, expiresAt := range s.expiries {
+		if !now.Before(expiresAt) {
+			s.deleteLocked(clientID)
+			evicted++
+		}
+	}
+
+	s.stats.Sweeps++
+	s.stats.Evicted += uint64(evicted)
+	s.stats.LastSweep = now
+	s.stats.LastEvicted = evicted
+
+	return evicted
+}
+
+// StartJanitor runs Sweep every interval in the background until Close is called.
+// Calling it while the janitor is already running has no effect. Like
+// time.NewTicker, it panics if interval is not positive.
+func (s *MemoryStorage) StartJanitor(interval time.Duration) {
+	s.janitor.start(interval, s.Sweep)
+}
+
+// Close stops the janitor and waits for it to exit. It is safe to call more than
+// once, and the storage remains usable afterwards.
+func (s *MemoryStorage) Close() error {
+	s.janitor.close()
+	return nil
+}
+
+func (s *MemoryStorage) JanitorStats() JanitorStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.stats
+}
+
+// janitor runs a sweep function on a ticker in a single background goroutine.
+type janitor struct {
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (j *janitor) start(interval time.Duration, sweep func(now time.Time) int) {
+	if interval <= 0 {
+		panic("storage: non-positive interval for StartJanitor")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	j.stop = stop
+	j.done = done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				sweep(now)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (j *janitor) close() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stop == nil {
+		return
+	}
+
+	close(j.stop)
+	<-j.done
+	j.stop = nil
+	j.done = nil
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSweep_EvictsExpiredWindows(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	storage.CheckAndIncrement("expired", now.Add(-2*time.Minute), time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("active", now, time.Minute, 10, time.Minute)
+
+	evicted := storage.Sweep(now)
+	if evicted != 1 {
+		t.Errorf("Expected 1 evicted client, got %d", evicted)
+	}
+
+	if _, exists := storage.GetClientData("expired"); exists {
+		t.Error("Expected expired client to be evicted")
+	}
+	if _, exists := storage.GetClientData("active"); !exists {
+		t.Error("Expected active client to be kept")
+	}
+}
+
+func TestSweep_KeepsBlockedClients(t *testing.T) {
+	storage := NewMemoryStorage()
+	start := time.Now().Add(-2 * time.Minute)
+
+	storage.CheckAndIncrement("blocked", start, time.Minute, 1, time.Hour)
+	storage.CheckAndIncrement("blocked", start, time.Minute, 1, time.Hour)
+
+	if evicted := storage.Sweep(time.Now()); evicted != 0 {
+		t.Errorf("Expected blocked client to be kept, evicted %d", evicted)
+	}
+
+	if evicted := storage.Sweep(start.Add(time.Hour)); evicted != 1 {
+		t.Errorf("Expected client to be evicted after the block expires, evicted %d", evicted)
+	}
+}
+
+func TestSweep_AllAlgorithms(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	storage.TakeToken("bucket", now, 1, 10, 5)
+	storage.SlidingWindowIncrement("window", now, time.Minute, 10, 1)
+	storage.AppendToLog("log", now, time.Minute, 10, 1)
+	storage.UpdateArrivalTime("gcra", now, time.Second, 10, 1)
+	storage.ScheduleLeak("leaky", now, time.Second, time.Minute, 1)
+
+	if evicted := storage.Sweep(now); evicted != 0 {
+		t.Errorf("Expected no clients to be evicted yet, evicted %d", evicted)
+	}
+
+	if evicted := storage.Sweep(now.Add(3 * time.Minute)); evicted != 5 {
+		t.Errorf("Expected all 5 clients to be evicted, evicted %d", evicted)
+	}
+}
+
+func TestJanitorStats(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	storage.CheckAndIncrement("client1", now, time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("client2", now, time.Minute, 10, time.Minute)
+
+	storage.Sweep(now)
+	storage.Sweep(now.Add(time.Minute))
+
+	stats := storage.JanitorStats()
+	if stats.Sweeps != 2 {
+		t.Errorf("Expected 2 sweeps, got %d", stats.Sweeps)
+	}
+	if stats.Evicted != 2 {
+		t.Errorf("Expected 2 evicted clients, got %d", stats.Evicted)
+	}
+	if stats.LastEvicted != 2 {
+		t.Errorf("Expected LastEvicted 2, got %d", stats.LastEvicted)
+	}
+	if !stats.LastSweep.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected LastSweep %v, got %v", now.Add(time.Minute), stats.LastSweep)
+	}
+}
+
+func TestStartJanitor(t *testing.T) {
+	storage := NewMemoryStorage()
+	defer storage.Close()
+
+	storage.CheckAndIncrement("test-client", time.Now(), 10*time.Millisecond, 10, 10*time.Millisecond)
+
+	storage.StartJanitor(5 * time.Millisecond)
+	storage.StartJanitor(5 * time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, exists := storage.GetClientData("test-client"); !exists {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, exists := storage.GetClientData("test-client"); exists {
+		t.Error("Expected janitor to evict the expired client")
+	}
+	if storage.JanitorStats().Evicted != 1 {
+		t.Errorf("Expected 1 evicted client, got %d", storage.JanitorStats().Evicted)
+	}
+}
+
+func TestClose_Idempotent(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	if err := storage.Close(); err != nil {
+		t.Errorf("Expected no error closing a storage without janitor, got %v", err)
+	}
+
+	storage.StartJanitor(time.Millisecond)
+	if err := storage.Close(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Errorf("Expected no error on second Close, got %v", err)
+	}
+
+	storage.StartJanitor(time.Millisecond)
+	storage.Close()
+}
+
+func TestStartJanitor_NonPositiveIntervalPanics(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		t.Run(interval.String(), func(t *testing.T) {
+			storage := NewMemoryStorage()
+			defer storage.Close()
+
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected StartJanitor to panic")
+				}
+			}()
+			storage.StartJanitor(interval)
+		})
+	}
+}
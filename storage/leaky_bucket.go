@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"time"
+)
+
+// ScheduleLeak reserves the next n slots in the client's leaky bucket queue, which
+// drains one unit per leakInterval. The request is admitted when the slot of its
+// last unit is at most maxDelay away; the returned time is the reserved slot, or
+// the queue tail when the request was rejected. A negative n gives up slots at the end of the
+// queue. A zero n only reports the queue tail without storing it.
+func (s *MemoryStorage) ScheduleLeak(clientID string, now time.Time, leakInterval time.Duration, maxDelay time.Duration, n int) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slot, exists := s.queues[clientID]
+	if !exists || slot.Before(now) {
+		slot = now
+	}
+
+	if n < 0 {
+		tail := slot.Add(leakInterval * time.Duration(n))
+		if tail.Before(now) {
+			tail = now
+		}
+		s.queues[clientID] = tail
+		s.touch(clientID, tail)
+		s.use(clientID, now, time.Time{})
+		return tail, true
+	}
+
+	if slot.Add(leakInterval*time.Duration(max(n-1, 0))).Sub(now) > maxDelay {
+		return slot, false
+	}
+	if n == 0 {
+		return slot, true
+	}
+
+	s.queues[clientID] = slot.Add(leakInterval * time.Duration(n))
+	s.touch(clientID, s.queues[clientID])
+	s.use(clientID, now, time.Time{})
+	return slot, true
+}
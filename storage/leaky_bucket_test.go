@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleLeak_QueuesRequests(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		slot, allowed := storage.ScheduleLeak("test-client", now, time.Second, 2*time.Second, 1)
+		if !allowed {
+			t.Errorf("Request %d should be queued", i+1)
+		}
+		if expected := now.Add(time.Duration(i) * time.Second); !slot.Equal(expected) {
+			t.Errorf("Expected slot %v, got %v", expected, slot)
+		}
+	}
+
+	slot, allowed := storage.ScheduleLeak("test-client", now, time.Second, 2*time.Second, 1)
+	if allowed {
+		t.Error("Request exceeding the queue should be rejected")
+	}
+	if expected := now.Add(3 * time.Second); !slot.Equal(expected) {
+		t.Errorf("Expected queue tail %v, got %v", expected, slot)
+	}
+}
+
+func TestScheduleLeak_Drains(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	storage.ScheduleLeak("test-client", now, time.Second, 0, 1)
+
+	if _, allowed := storage.ScheduleLeak("test-client", now, time.Second, 0, 1); allowed {
+		t.Error("Request should be rejected while the previous one is draining")
+	}
+
+	slot, allowed := storage.ScheduleLeak("test-client", now.Add(5*time.Second), time.Second, 0, 1)
+	if !allowed {
+		t.Error("Request should be allowed once the queue has drained")
+	}
+	if !slot.Equal(now.Add(5 * time.Second)) {
+		t.Errorf("Expected immediate slot, got %v", slot)
+	}
+}
+
+func TestScheduleLeak_CostCannotOverflowQueue(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	storage.ScheduleLeak("test-client", now, time.Second, 2*time.Second, 1)
+	storage.ScheduleLeak("test-client", now, time.Second, 2*time.Second, 1)
+
+	slot, allowed := storage.ScheduleLeak("test-client", now, time.Second, 2*time.Second, 3)
+	if allowed {
+		t.Error("Request whose last unit exceeds the queue should be rejected")
+	}
+	if expected := now.Add(2 * time.Second); !slot.Equal(expected) {
+		t.Errorf("Expected queue tail %v, got %v", expected, slot)
+	}
+
+	if _, allowed := storage.ScheduleLeak("test-client", now, time.Second, 2*time.Second, 1); !allowed {
+		t.Error("Request fitting the last slot should be queued")
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"container/list"
+	"time"
+)
+
+type EvictionPolicy int
+
+const (
+	// EvictLeastRecentlyUsed evicts the least recently used client even if it is
+	// blocked. An evicted client starts over with a fresh quota.
+	EvictLeastRecentlyUsed EvictionPolicy = iota
+	// PreserveBlocked evicts the least recently used unblocked client, and only
+	// evicts a blocked client when every tracked client is blocked.
+	PreserveBlocked
+)
+
+type lruEntry struct {
+	clientID     string
+	tick         uint64
+	blockedUntil time.Time
+}
+
+// NewBoundedMemoryStorage returns a MemoryStorage that tracks at most maxClients
+// clients, evicting the least recently used one according to policy when a new
+// client arrives at capacity. Blocking refers to the fixed window BlockedUntil.
+func NewBoundedMemoryStorage(maxClients int, policy EvictionPolicy) *MemoryStorage {
+	s := NewMemoryStorage()
+	s.maxClients = maxClients
+	s.policy = policy
+	return s
+}
+
+// Evictions returns how many clients have been evicted to stay within capacity.
+func (s *MemoryStorage) Evictions() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.evictions
+}
+
+// use marks clientID as most recently used and evicts clients beyond maxClients.
+// Must be called with s.mu held.
+func (s *MemoryStorage) use(clientID string, now time.Time, blockedUntil time.Time) {
+	if s.maxClients <= 0 {
+		return
+	}
+
+	s.tick++
+	entry := &lruEntry{clientID: clientID, tick: s.tick, blockedUntil: blockedUntil}
+
+	s.forget(clientID)
+	s.lruIndex[clientID] = s.listFor(entry, now).PushFront(entry)
+
+	for len(s.lruIndex) > s.maxClients {
+		victim := s.victim(clientID, now)
+		if victim == "" {
+			return
+		}
+		s.deleteLocked(victim)
+		s.evictions++
+	}
+}
+
+func (s *MemoryStorage) listFor(entry *lruEntry, now time.Time) *list.List {
+	if entry.blockedUntil.After(now) {
+		return s.blockedLRU
+	}
+	return s.lru
+}
+
+func (s *MemoryStorage) victim(exclude string, now time.Time) string {
+	unblocked := oldest(s.lru, exclude)
+	blocked := oldest(s.blockedLRU, exclude)
+
+	if blocked != nil && !blocked.blockedUntil.After(now) {
+		if unblocked == nil || blocked.tick < unblocked.tick {
+			return blocked.clientID
+		}
+	}
+
+	switch {
+	case unblocked == nil && blocked == nil:
+		return ""
+	case unblocked == nil:
+		return blocked.clientID
+	case blocked == nil:
+		return unblocked.clientID
+	case s.policy == PreserveBlocked || unblocked.tick < blocked.tick:
+		return unblocked.clientID
+	default:
+		return blocked.clientID
+	}
+}
+
+func oldest(l *list.List, exclude string) *lruEntry {
+	for elem := l.Back(); elem != nil; elem = elem.Prev() {
+		if entry := elem.Value.(*lruEntry); entry.clientID != exclude {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) forget(clientID string) {
+	if elem, exists := s.lruIndex[clientID]; exists {
+		s.lru.Remove(elem)
+		s.blockedLRU.Remove(elem)
+		delete(s.lruIndex, clientID)
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBoundedMemoryStorage_EvictsLeastRecentlyUsed(t *testing.T) {
+	storage := NewBoundedMemoryStorage(2, EvictLeastRecentlyUsed)
+	now := time.Now()
+
+	storage.CheckAndIncrement("client1", now, time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("client2", now, time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("client1", now, time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("client3", now, time.Minute, 10, time.Minute)
+
+	if _, exists := storage.GetClientData("client2"); exists {
+		t.Error("Expected least recently used client2 to be evicted")
+	}
+	if _, exists := storage.GetClientData("client1"); !exists {
+		t.Error("Expected recently used client1 to be kept")
+	}
+	if _, exists := storage.GetClientData("client3"); !exists {
+		t.Error("Expected new client3 to be kept")
+	}
+	if storage.Evictions() != 1 {
+		t.Errorf("Expected 1 eviction, got %d", storage.Evictions())
+	}
+}
+
+func TestBoundedMemoryStorage_Cap(t *testing.T) {
+	storage := NewBoundedMemoryStorage(100, EvictLeastRecentlyUsed)
+	now := time.Now()
+
+	for i := 0; i < 1000; i++ {
+		clientID := fmt.Sprintf("client-%d", i)
+		storage.CheckAndIncrement(clientID, now, time.Minute, 10, time.Minute)
+		storage.TakeToken(clientID, now, 1, 10, 1)
+	}
+
+	if len(storage.clients) != 100 || len(storage.buckets) != 100 {
+		t.Errorf("Expected 100 tracked clients, got %d clients and %d buckets", len(storage.clients), len(storage.buckets))
+	}
+	if storage.Evictions() != 900 {
+		t.Errorf("Expected 900 evictions, got %d", storage.Evictions())
+	}
+}
+
+func TestBoundedMemoryStorage_EvictLeastRecentlyUsedForgetsBlock(t *testing.T) {
+	storage := NewBoundedMemoryStorage(1, EvictLeastRecentlyUsed)
+	now := time.Now()
+
+	storage.CheckAndIncrement("blocked", now, time.Minute, 1, time.Hour)
+	storage.CheckAndIncrement("blocked", now, time.Minute, 1, time.Hour)
+	storage.CheckAndIncrement("other", now, time.Minute, 1, time.Hour)
+
+	if _, exists := storage.GetClientData("blocked"); exists {
+		t.Error("Expected blocked client to be evicted under EvictLeastRecentlyUsed")
+	}
+}
+
+func TestBoundedMemoryStorage_PreserveBlocked(t *testing.T) {
+	storage := NewBoundedMemoryStorage(2, PreserveBlocked)
+	now := time.Now()
+
+	storage.CheckAndIncrement("blocked", now, time.Minute, 1, time.Hour)
+	storage.CheckAndIncrement("blocked", now, time.Minute, 1, time.Hour)
+	storage.CheckAndIncrement("client1", now, time.Minute, 1, time.Hour)
+	storage.CheckAndIncrement("client2", now, time.Minute, 1, time.Hour)
+
+	data, exists := storage.GetClientData("blocked")
+	if !exists {
+		t.Fatal("Expected blocked client to be preserved")
+	}
+	if !data.BlockedUntil.After(now) {
+		t.Error("Expected preserved client to still be blocked")
+	}
+	if _, exists := storage.GetClientData("client1"); exists {
+		t.Error("Expected unblocked client1 to be evicted instead")
+	}
+}
+
+func TestBoundedMemoryStorage_PreserveBlockedAtCapacity(t *testing.T) {
+	storage := NewBoundedMemoryStorage(2, PreserveBlocked)
+	now := time.Now()
+
+	for _, clientID := range []string{"blocked1", "blocked2"} {
+		storage.CheckAndIncrement(clientID, now, time.Minute, 1, time.Hour)
+		storage.CheckAndIncrement(clientID, now, time.Minute, 1, time.Hour)
+	}
+	storage.CheckAndIncrement("new", now, time.Minute, 1, time.Hour)
+
+	if len(storage.clients) != 2 {
+		t.Errorf("Expected capacity to hold when every client is blocked, got %d clients", len(storage.clients))
+	}
+	if _, exists := storage.GetClientData("blocked1"); exists {
+		t.Error("Expected the least recently used blocked client to be evicted")
+	}
+	if _, exists := storage.GetClientData("new"); !exists {
+		t.Error("Expected the new client to be tracked")
+	}
+}
+
+func TestBoundedMemoryStorage_DeleteAndClear(t *testing.T) {
+	storage := NewBoundedMemoryStorage(2, EvictLeastRecentlyUsed)
+	now := time.Now()
+
+	storage.CheckAndIncrement("client1", now, time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("client2", now, time.Minute, 10, time.Minute)
+	storage.DeleteClient("client1")
+	storage.CheckAndIncrement("client3", now, time.Minute, 10, time.Minute)
+
+	if storage.Evictions() != 0 {
+		t.Errorf("Expected no evictions after a deletion freed a slot, got %d", storage.Evictions())
+	}
+
+	storage.Clear()
+	storage.CheckAndIncrement("client4", now, time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("client5", now, time.Minute, 10, time.Minute)
+
+	if storage.Evictions() != 0 {
+		t.Errorf("Expected no evictions after Clear, got %d", storage.Evictions())
+	}
+}
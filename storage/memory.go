@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"container/list"
 	"sync"
 	"time"
 )
@@ -12,13 +13,40 @@ type ClientData struct {
 }
 
 type MemoryStorage struct {
-	mu      sync.RWMutex
-	clients map[string]*ClientData
+	mu       sync.RWMutex
+	clients  map[string]*ClientData
+	buckets  map[string]*TokenBucket
+	windows  map[string]*WindowCounter
+	logs     map[string]*RequestLog
+	arrivals map[string]time.Time
+	queues   map[string]time.Time
+	expiries map[string]time.Time
+	stats    JanitorStats
+
+	maxClients int
+	policy     EvictionPolicy
+	lru        *list.List
+	blockedLRU *list.List
+	lruIndex   map[string]*list.Element
+	tick       uint64
+	evictions  uint64
+
+	janitor janitor
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		clients: make(map[string]*ClientData),
+		clients:  make(map[string]*ClientData),
+		buckets:  make(map[string]*TokenBucket),
+		windows:  make(map[string]*WindowCounter),
+		logs:     make(map[string]*RequestLog),
+		arrivals: make(map[string]time.Time),
+		queues:   make(map[string]time.Time),
+		expiries: make(map[string]time.Time),
+
+		lru:        list.New(),
+		blockedLRU: list.New(),
+		lruIndex:   make(map[string]*list.Element),
 	}
 }
 
@@ -48,6 +76,7 @@ func (s *MemoryStorage) SetClientData(clientID string, data *ClientData) {
 		WindowStart:  data.WindowStart,
 		BlockedUntil: data.BlockedUntil,
 	}
+	s.use(clientID, time.Now(), data.BlockedUntil)
 }
 
 func (s *MemoryStorage) IncrementRequestCount(clientID string) int {
@@ -56,6 +85,7 @@ func (s *MemoryStorage) IncrementRequestCount(clientID string) int {
 	
 	if data, exists := s.clients[clientID]; exists {
 		data.RequestCount++
+		s.use(clientID, time.Now(), data.BlockedUntil)
 		return data.RequestCount
 	}
 	
@@ -64,6 +94,7 @@ func (s *MemoryStorage) IncrementRequestCount(clientID string) int {
 		WindowStart:  time.Now(),
 		BlockedUntil: time.Time{},
 	}
+	s.use(clientID, time.Now(), time.Time{})
 	return 1
 }
 
@@ -76,6 +107,7 @@ func (s *MemoryStorage) ResetWindow(clientID string, windowStart time.Time) {
 		WindowStart:  windowStart,
 		BlockedUntil: time.Time{},
 	}
+	s.use(clientID, time.Now(), time.Time{})
 }
 
 func (s *MemoryStorage) BlockClient(clientID string, blockedUntil time.Time) {
@@ -84,6 +116,7 @@ func (s *MemoryStorage) BlockClient(clientID string, blockedUntil time.Time) {
 	
 	if data, exists := s.clients[clientID]; exists {
 		data.BlockedUntil = blockedUntil
+		s.use(clientID, time.Now(), blockedUntil)
 	}
 }
 
@@ -91,7 +124,7 @@ func (s *MemoryStorage) DeleteClient(clientID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
-	delete(s.clients, clientID)
+	s.deleteLocked(clientID)
 }
 
 func (s *MemoryStorage) Clear() {
@@ -99,71 +132,94 @@ func (s *MemoryStorage) Clear() {
 	defer s.mu.Unlock()
 	
 	s.clients = make(map[string]*ClientData)
+	s.buckets = make(map[string]*TokenBucket)
+	s.windows = make(map[string]*WindowCounter)
+	s.logs = make(map[string]*RequestLog)
+	s.arrivals = make(map[string]time.Time)
+	s.queues = make(map[string]time.Time)
+	s.expiries = make(map[string]time.Time)
+	s.lru.Init()
+	s.blockedLRU.Init()
+	s.lruIndex = make(map[string]*list.Element)
 }
 
 func (s *MemoryStorage) CheckAndIncrement(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration) (*ClientData, bool) {
+	return s.CheckAndIncrementN(clientID, now, windowDuration, maxRequests, blockDuration, 1)
+}
+
+// CheckAndIncrementN charges n requests against the client's current window. A
+// request arriving once the window is used up is counted and blocks the client;
+// one that only fails to fit in the room left is denied without being charged.
+// A negative n releases previously granted requests without affecting blocking,
+// and a zero n only reports the client's window without storing it.
+func (s *MemoryStorage) CheckAndIncrementN(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*ClientData, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	
+
 	data, exists := s.clients[clientID]
-	
-	if exists && !data.BlockedUntil.IsZero() && now.Before(data.BlockedUntil) {
+
+	if n < 0 {
+		if !exists {
+			return nil, true
+		}
+		if now.Sub(data.WindowStart) < windowDuration {
+			data.RequestCount = max(0, data.RequestCount+n)
+		}
 		dataCopy := &ClientData{
 			RequestCount: data.RequestCount,
 			WindowStart:  data.WindowStart,
 			BlockedUntil: data.BlockedUntil,
 		}
-		return dataCopy, false
-	}
-	
-	if exists && (!data.BlockedUntil.IsZero() && now.After(data.BlockedUntil)) {
-		s.clients[clientID] = &ClientData{
-			RequestCount: 1,
-			WindowStart:  now,
-			BlockedUntil: time.Time{},
-		}
-		dataCopy := &ClientData{
-			RequestCount: 1,
-			WindowStart:  now,
-			BlockedUntil: time.Time{},
-		}
 		return dataCopy, true
 	}
-	
-	if exists && now.Sub(data.WindowStart) >= windowDuration {
-		s.clients[clientID] = &ClientData{
-			RequestCount: 1,
-			WindowStart:  now,
-			BlockedUntil: time.Time{},
+
+	if exists && !data.BlockedUntil.IsZero() && now.Before(data.BlockedUntil) {
+		if n > 0 {
+			s.touch(clientID, data.BlockedUntil)
+			s.use(clientID, now, data.BlockedUntil)
 		}
 		dataCopy := &ClientData{
-			RequestCount: 1,
-			WindowStart:  now,
-			BlockedUntil: time.Time{},
+			RequestCount: data.RequestCount,
+			WindowStart:  data.WindowStart,
+			BlockedUntil: data.BlockedUntil,
 		}
-		return dataCopy, true
+		return dataCopy, false
 	}
-	
-	if !exists {
-		s.clients[clientID] = &ClientData{
-			RequestCount: 1,
+
+	blockExpired := exists && !data.BlockedUntil.IsZero() && now.After(data.BlockedUntil)
+	windowExpired := exists && now.Sub(data.WindowStart) >= windowDuration
+
+	if !exists || blockExpired || windowExpired {
+		data = &ClientData{
+			RequestCount: 0,
 			WindowStart:  now,
 			BlockedUntil: time.Time{},
 		}
+	}
+
+	if n == 0 || (data.RequestCount < maxRequests && data.RequestCount+n > maxRequests) {
 		dataCopy := &ClientData{
-			RequestCount: 1,
-			WindowStart:  now,
-			BlockedUntil: time.Time{},
+			RequestCount: data.RequestCount,
+			WindowStart:  data.WindowStart,
+			BlockedUntil: data.BlockedUntil,
 		}
-		return dataCopy, true
+		return dataCopy, n == 0
 	}
-	
-	data.RequestCount++
-	
+
+	s.clients[clientID] = data
+	data.RequestCount += n
+
 	if data.RequestCount > maxRequests {
 		data.BlockedUntil = now.Add(blockDuration)
 	}
-	
+
+	expiresAt := data.WindowStart.Add(windowDuration)
+	if data.BlockedUntil.After(expiresAt) {
+		expiresAt = data.BlockedUntil
+	}
+	s.touch(clientID, expiresAt)
+	s.use(clientID, now, data.BlockedUntil)
+
 	dataCopy := &ClientData{
 		RequestCount: data.RequestCount,
 		WindowStart:  data.WindowStart,
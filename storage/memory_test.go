@@ -251,3 +251,55 @@ func TestConcurrentReadWrite(t *testing.T) {
 		t.Errorf("Expected RequestCount 50, got %d", data.RequestCount)
 	}
 }
+
+func TestCheckAndIncrementN(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	data, allowed := storage.CheckAndIncrementN("test-client", now, time.Minute, 10, time.Minute, 4)
+	if !allowed {
+		t.Error("Expected request to be allowed")
+	}
+	if data.RequestCount != 4 {
+		t.Errorf("Expected RequestCount 4, got %d", data.RequestCount)
+	}
+
+	data, allowed = storage.CheckAndIncrementN("test-client", now, time.Minute, 10, time.Minute, 7)
+	if allowed || data.RequestCount != 4 || !data.BlockedUntil.IsZero() {
+		t.Errorf("Expected a cost that does not fit to be denied uncharged, got %v/%+v", allowed, data)
+	}
+
+	storage.CheckAndIncrementN("test-client", now, time.Minute, 10, time.Minute, 6)
+	data, _ = storage.CheckAndIncrementN("test-client", now, time.Minute, 10, time.Minute, 1)
+	if data.RequestCount != 11 {
+		t.Errorf("Expected RequestCount 11, got %d", data.RequestCount)
+	}
+	if !data.BlockedUntil.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected client to be blocked until %v, got %v", now.Add(time.Minute), data.BlockedUntil)
+	}
+
+	if _, allowed := storage.CheckAndIncrementN("test-client", now.Add(time.Second), time.Minute, 10, time.Minute, 1); allowed {
+		t.Error("Expected blocked client to be denied")
+	}
+}
+
+func TestZeroCostLeavesStorageEmpty(t *testing.T) {
+	storage := NewBoundedMemoryStorage(1, EvictLeastRecentlyUsed)
+	now := time.Now()
+
+	storage.CheckAndIncrementN("unknown", now, time.Minute, 10, time.Minute, 0)
+	storage.TakeToken("unknown", now, 1, 10, 0)
+	storage.SlidingWindowIncrement("unknown", now, time.Minute, 10, 0)
+	storage.AppendToLog("unknown", now, time.Minute, 10, 0)
+	storage.UpdateArrivalTime("unknown", now, time.Second, 10, 0)
+	storage.ScheduleLeak("unknown", now, time.Second, time.Minute, 0)
+
+	tracked := len(storage.clients) + len(storage.buckets) + len(storage.windows) + len(storage.logs) +
+		len(storage.arrivals) + len(storage.queues) + len(storage.expiries) + len(storage.lruIndex)
+	if tracked != 0 {
+		t.Errorf("Expected zero-cost calls to store nothing, got %d entries", tracked)
+	}
+	if storage.Evictions() != 0 {
+		t.Errorf("Expected no evictions, got %d", storage.Evictions())
+	}
+}
@@ -0,0 +1,231 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+type client struct {
+	addr        string
+	password    string
+	db          int
+	dialTimeout time.Duration
+	ioTimeout   time.Duration
+	idle        chan *conn
+}
+
+func (c *client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: c.dialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+		writer:  bufio.NewWriter(netConn),
+	}
+
+	if c.password != "" {
+		if _, err := c.roundTrip(ctx, cn, "AUTH", c.password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip(ctx, cn, "SELECT", strconv.Itoa(c.db)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+func (c *client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+		return c.dial(ctx)
+	}
+}
+
+func (c *client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.netConn.Close()
+	}
+}
+
+func (c *client) close() {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.netConn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (c *client) do(ctx context.Context, args ...string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.roundTrip(ctx, cn, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		cn.netConn.Close()
+		return nil, err
+	}
+
+	c.put(cn)
+	return reply, err
+}
+
+// roundTrip sends one command and reads its reply. The connection deadline is the
+// earlier of the I/O timeout and the context deadline, and canceling ctx aborts
+// the exchange.
+func (c *client) roundTrip(ctx context.Context, cn *conn, args ...string) (any, error) {
+	var deadline time.Time
+	if c.ioTimeout > 0 {
+		deadline = time.Now().Add(c.ioTimeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	cn.netConn.SetDeadline(deadline)
+
+	stop := context.AfterFunc(ctx, func() {
+		cn.netConn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	reply, err := c.exchange(cn, args...)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return reply, err
+}
+
+func (c *client) exchange(cn *conn, args ...string) (any, error) {
+
+	fmt.Fprintf(cn.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(cn.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := cn.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	return readReply(cn.reader)
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+	}
+}
+
+type script struct {
+	source string
+	sha    string
+}
+
+func newScript(source string) *script {
+	sum := sha1.Sum([]byte(source))
+	return &script{
+		source: source,
+		sha:    hex.EncodeToString(sum[:]),
+	}
+}
+
+// eval runs the script by hash, loading it with EVAL the first time a server
+// reports it missing.
+func (c *client) eval(ctx context.Context, s *script, keys []string, args ...string) (any, error) {
+	cmd := make([]string, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, "EVALSHA", s.sha, strconv.Itoa(len(keys)))
+	cmd = append(cmd, keys...)
+	cmd = append(cmd, args...)
+
+	reply, err := c.do(ctx, cmd...)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		cmd[0], cmd[1] = "EVAL", s.source
+		return c.do(ctx, cmd...)
+	}
+	return reply, err
+}
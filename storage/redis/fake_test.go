@@ -0,0 +1,462 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer is a minimal in-process RESP server used when REDIS_ADDR is not
+// set. Each Lua script is mirrored by a Go function with the same semantics.
+type fakeServer struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	hashes  map[string]map[string]string
+	strs    map[string]string
+	zsets   map[string][]zmember
+	loaded  map[string]bool
+	scripts map[string]func(keys, args []string) any
+}
+
+type zmember struct {
+	score  float64
+	member string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	f := &fakeServer{
+		listener: listener,
+		hashes:   make(map[string]map[string]string),
+		strs:     make(map[string]string),
+		zsets:    make(map[string][]zmember),
+		loaded:   make(map[string]bool),
+	}
+	f.scripts = map[string]func(keys, args []string) any{
+		fixedWindowScript.sha:   f.fixedWindow,
+		tokenBucketScript.sha:   f.tokenBucket,
+		slidingWindowScript.sha: f.slidingWindow,
+		slidingLogScript.sha:    f.slidingLog,
+		gcraScript.sha:          f.gcra,
+		leakyBucketScript.sha:   f.leakyBucket,
+	}
+
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return f
+}
+
+func (f *fakeServer) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeServer) serve() {
+	for {
+		c, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(c)
+	}
+}
+
+func (f *fakeServer) handle(c net.Conn) {
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		reply := f.exec(cmd)
+		f.mu.Unlock()
+
+		writeReply(w, reply)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := readReply(r)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, io.ErrUnexpectedEOF
+	}
+	cmd := make([]string, len(items))
+	for i, item := range items {
+		cmd[i], _ = item.(string)
+	}
+	return cmd, nil
+}
+
+func writeReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case redisError:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	}
+}
+
+func (f *fakeServer) exec(cmd []string) any {
+	switch strings.ToUpper(cmd[0]) {
+	case "PING":
+		return "PONG"
+	case "AUTH", "SELECT":
+		return "OK"
+	case "HMGET":
+		hash := f.hashes[cmd[1]]
+		values := make([]any, 0, len(cmd)-2)
+		for _, field := range cmd[2:] {
+			if v, ok := hash[field]; ok {
+				values = append(values, v)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		return values
+	case "EXISTS":
+		var found int64
+		for _, key := range cmd[1:] {
+			if _, ok := f.hashes[key]; ok {
+				found++
+			} else if _, ok := f.strs[key]; ok {
+				found++
+			} else if _, ok := f.zsets[key]; ok {
+				found++
+			}
+		}
+		return found
+	case "DEL":
+		var deleted int64
+		for _, key := range cmd[1:] {
+			if f.del(key) {
+				deleted++
+			}
+		}
+		return deleted
+	case "SCAN":
+		pattern := strings.TrimSuffix(cmd[3], "*")
+		var keys []any
+		for _, key := range f.allKeys() {
+			if strings.HasPrefix(key, pattern) {
+				keys = append(keys, key)
+			}
+		}
+		return []any{"0", keys}
+	case "EVALSHA":
+		if !f.loaded[cmd[1]] {
+			return redisError("NOSCRIPT No matching script. Please use EVAL.")
+		}
+		return f.eval(cmd[1], cmd[2:])
+	case "EVAL":
+		sha := newScript(cmd[1]).sha
+		f.loaded[sha] = true
+		return f.eval(sha, cmd[2:])
+	default:
+		return redisError("ERR unknown command '" + cmd[0] + "'")
+	}
+}
+
+func (f *fakeServer) eval(sha string, rest []string) any {
+	fn, ok := f.scripts[sha]
+	if !ok {
+		return redisError("ERR unknown script")
+	}
+	numKeys, _ := strconv.Atoi(rest[0])
+	return fn(rest[1:1+numKeys], rest[1+numKeys:])
+}
+
+func (f *fakeServer) hset(key string, pairs ...string) {
+	hash, ok := f.hashes[key]
+	if !ok {
+		hash = make(map[string]string)
+		f.hashes[key] = hash
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		hash[pairs[i]] = pairs[i+1]
+	}
+}
+
+func (f *fakeServer) del(key string) bool {
+	_, inHashes := f.hashes[key]
+	_, inStrs := f.strs[key]
+	_, inZsets := f.zsets[key]
+	delete(f.hashes, key)
+	delete(f.strs, key)
+	delete(f.zsets, key)
+	return inHashes || inStrs || inZsets
+}
+
+func (f *fakeServer) allKeys() []string {
+	var keys []string
+	for key := range f.hashes {
+		keys = append(keys, key)
+	}
+	for key := range f.strs {
+		keys = append(keys, key)
+	}
+	for key := range f.zsets {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (f *fakeServer) keyCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.allKeys())
+}
+
+func num(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func field(hash map[string]string, name string) (float64, bool) {
+	v, ok := hash[name]
+	if !ok {
+		return 0, false
+	}
+	return num(v), true
+}
+
+func itoa(v float64) string {
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
+
+func (f *fakeServer) fixedWindow(keys, args []string) any {
+	now, window, max, block, n := num(args[0]), num(args[1]), num(args[2]), num(args[3]), num(args[4])
+
+	hash := f.hashes[keys[0]]
+	count, exists := field(hash, "count")
+	start, _ := field(hash, "start")
+	blocked, _ := field(hash, "blocked")
+
+	if n < 0 {
+		if !exists {
+			return []any{int64(0), int64(0), int64(0), int64(1), int64(0)}
+		}
+		if now-start < window {
+			count = math.Max(0, count+n)
+			f.hset(keys[0], "count", itoa(count))
+		}
+		return []any{int64(count), int64(start), int64(blocked), int64(1), int64(1)}
+	}
+
+	if exists && blocked != 0 && now < blocked {
+		return []any{int64(count), int64(start), int64(blocked), int64(0), int64(1)}
+	}
+
+	if !exists || (blocked != 0 && now > blocked) || now-start >= window {
+		count, start, blocked = 0, now, 0
+	}
+
+	if n == 0 {
+		return []any{int64(count), int64(start), int64(blocked), int64(1), int64(1)}
+	}
+	if count < max && count+n > max {
+		return []any{int64(count), int64(start), int64(blocked), int64(0), int64(1)}
+	}
+
+	count += n
+	if count > max {
+		blocked = now + block
+	}
+
+	f.hset(keys[0], "count", itoa(count), "start", itoa(start), "blocked", itoa(blocked))
+	return []any{int64(count), int64(start), int64(blocked), int64(1), int64(1)}
+}
+
+func (f *fakeServer) tokenBucket(keys, args []string) any {
+	now, rate, capacity, n := num(args[0]), num(args[1]), num(args[2]), num(args[3])
+
+	hash := f.hashes[keys[0]]
+	tokens, exists := field(hash, "tokens")
+	last, _ := field(hash, "last")
+	if !exists {
+		tokens, last = capacity, now
+	}
+
+	if now > last {
+		tokens = math.Min(capacity, tokens+(now-last)/1e6*rate)
+		last = now
+	}
+
+	var allowed int64
+	if tokens >= n {
+		tokens = math.Min(tokens-n, capacity)
+		allowed = 1
+	}
+
+	encoded := strconv.FormatFloat(tokens, 'g', 17, 64)
+	if n == 0 {
+		return []any{encoded, int64(last), allowed}
+	}
+	f.hset(keys[0], "tokens", encoded, "last", itoa(last))
+	return []any{encoded, int64(last), allowed}
+}
+
+func (f *fakeServer) slidingWindow(keys, args []string) any {
+	now, window, max, n := num(args[0]), num(args[1]), num(args[2]), num(args[3])
+	start := now - math.Mod(now, window)
+
+	hash := f.hashes[keys[0]]
+	current, _ := field(hash, "current")
+	previous, _ := field(hash, "previous")
+	windowStart, exists := field(hash, "start")
+	if !exists {
+		windowStart = start
+	}
+
+	if start > windowStart {
+		if start-windowStart == window {
+			previous = current
+		} else {
+			previous = 0
+		}
+		current = 0
+		windowStart = start
+	}
+
+	weight := math.Max(0, 1-(now-windowStart)/window)
+	var allowed int64
+	if n < 0 || previous*weight+current+n <= max {
+		current = math.Max(0, current+n)
+		allowed = 1
+	}
+	if n == 0 {
+		return []any{int64(current), int64(previous), int64(windowStart), allowed}
+	}
+
+	f.hset(keys[0], "current", itoa(current), "previous", itoa(previous), "start", itoa(windowStart))
+	return []any{int64(current), int64(previous), int64(windowStart), allowed}
+}
+
+func (f *fakeServer) slidingLog(keys, args []string) any {
+	now, window, max, n := num(args[0]), num(args[1]), num(args[2]), int(num(args[3]))
+
+	var entries []zmember
+	for _, entry := range f.zsets[keys[0]] {
+		if entry.score > now-window {
+			entries = append(entries, entry)
+		}
+	}
+
+	var allowed int64
+	if n == 0 {
+		if len(entries) <= int(max) {
+			allowed = 1
+		}
+		result := []any{allowed}
+		for _, entry := range entries {
+			result = append(result, itoa(entry.score))
+		}
+		return result
+	}
+	if n < 0 {
+		entries = entries[:len(entries)-min(len(entries), -n)]
+		allowed = 1
+	} else if len(entries)+n <= int(max) {
+		for i := 0; i < n; i++ {
+			seq := num(f.strs[keys[1]]) + 1
+			f.strs[keys[1]] = itoa(seq)
+			entries = append(entries, zmember{score: now, member: itoa(seq)})
+		}
+		allowed = 1
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].score < entries[j].score })
+
+	if len(entries) == 0 {
+		f.del(keys[0])
+		f.del(keys[1])
+	} else {
+		f.zsets[keys[0]] = entries
+	}
+
+	result := []any{allowed}
+	for _, entry := range entries {
+		result = append(result, itoa(entry.score))
+	}
+	return result
+}
+
+func (f *fakeServer) gcra(keys, args []string) any {
+	now, interval, burst, n := num(args[0]), num(args[1]), num(args[2]), num(args[3])
+
+	tat, exists := f.strs[keys[0]]
+	t := num(tat)
+	if !exists || t < now {
+		t = now
+	}
+
+	newTAT := t + interval*n
+	if n >= 0 && now < newTAT-interval*burst {
+		return []any{int64(t), int64(0)}
+	}
+	if n == 0 {
+		return []any{int64(t), int64(1)}
+	}
+	newTAT = math.Max(newTAT, now)
+
+	f.strs[keys[0]] = itoa(newTAT)
+	return []any{int64(newTAT), int64(1)}
+}
+
+func (f *fakeServer) leakyBucket(keys, args []string) any {
+	now, interval, maxDelay, n := num(args[0]), num(args[1]), num(args[2]), num(args[3])
+
+	value, exists := f.strs[keys[0]]
+	slot := num(value)
+	if !exists || slot < now {
+		slot = now
+	}
+
+	if n >= 0 && slot+interval*math.Max(n-1, 0)-now > maxDelay {
+		return []any{int64(slot), int64(0)}
+	}
+	if n == 0 {
+		return []any{int64(slot), int64(1)}
+	}
+
+	tail := math.Max(now, slot+interval*n)
+	f.strs[keys[0]] = itoa(tail)
+
+	if n < 0 {
+		return []any{int64(tail), int64(1)}
+	}
+	return []any{int64(slot), int64(1)}
+}
@@ -0,0 +1,357 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+)
+
+var errUnexpectedReply = errors.New("redis: unexpected reply")
+
+// Storage keeps rate limit state in Redis so that every replica behind a load
+// balancer shares one quota per client. It implements ratelimiter.StorageV2. Each
+// check runs as a Lua script, making it atomic on the server, and every key
+// carries a TTL so idle clients expire on their own.
+type Storage struct {
+	client    *client
+	keyPrefix string
+}
+
+type Option func(*Storage)
+
+func WithPassword(password string) Option {
+	return func(s *Storage) {
+		s.client.password = password
+	}
+}
+
+func WithDB(db int) Option {
+	return func(s *Storage) {
+		s.client.db = db
+	}
+}
+
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Storage) {
+		s.keyPrefix = prefix
+	}
+}
+
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(s *Storage) {
+		s.client.dialTimeout = timeout
+	}
+}
+
+func WithIOTimeout(timeout time.Duration) Option {
+	return func(s *Storage) {
+		s.client.ioTimeout = timeout
+	}
+}
+
+func WithPoolSize(size int) Option {
+	return func(s *Storage) {
+		s.client.idle = make(chan *conn, size)
+	}
+}
+
+func New(addr string, opts ...Option) *Storage {
+	s := &Storage{
+		client: &client{
+			addr:        addr,
+			dialTimeout: time.Second,
+			ioTimeout:   time.Second,
+			idle:        make(chan *conn, 16),
+		},
+		keyPrefix: "ratelimit:",
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	_, err := s.client.do(ctx, "PING")
+	return err
+}
+
+func (s *Storage) Close() error {
+	s.client.close()
+	return nil
+}
+
+// key builds a per-client key. The client ID is wrapped in a hash tag so that all
+// of a client's keys land in the same Redis Cluster slot.
+func (s *Storage) key(clientID, suffix string) string {
+	return s.keyPrefix + "{" + clientID + "}:" + suffix
+}
+
+func (s *Storage) keys(clientID string) []string {
+	return []string{
+		s.key(clientID, "fw"),
+		s.key(clientID, "tb"),
+		s.key(clientID, "sw"),
+		s.key(clientID, "sl"),
+		s.key(clientID, "sl:seq"),
+		s.key(clientID, "gcra"),
+		s.key(clientID, "lb"),
+	}
+}
+
+func micros(t time.Time) string {
+	return strconv.FormatInt(t.UnixMicro(), 10)
+}
+
+func durationMicros(d time.Duration) string {
+	return strconv.FormatInt(d.Microseconds(), 10)
+}
+
+func fromMicros(v int64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(v)
+}
+
+func (s *Storage) GetClientData(ctx context.Context, clientID string) (*storage.ClientData, bool, error) {
+	reply, err := s.client.do(ctx, "HMGET", s.key(clientID, "fw"), "count", "start", "blocked")
+	if err != nil {
+		return nil, false, err
+	}
+
+	fields, err := bulkStrings(reply, 3)
+	if err != nil {
+		return nil, false, err
+	}
+	if fields[0] == nil {
+		return nil, false, nil
+	}
+
+	return &storage.ClientData{
+		RequestCount: int(parseInt(fields[0])),
+		WindowStart:  fromMicros(parseInt(fields[1])),
+		BlockedUntil: fromMicros(parseInt(fields[2])),
+	}, true, nil
+}
+
+func (s *Storage) DeleteClient(ctx context.Context, clientID string) error {
+	_, err := s.client.do(ctx, append([]string{"DEL"}, s.keys(clientID)...)...)
+	return err
+}
+
+// Clear deletes every key under the storage's key prefix.
+func (s *Storage) Clear(ctx context.Context) error {
+	cursor := "0"
+	for {
+		reply, err := s.client.do(ctx, "SCAN", cursor, "MATCH", s.keyPrefix+"*", "COUNT", "100")
+		if err != nil {
+			return err
+		}
+
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return errUnexpectedReply
+		}
+		cursor, _ = items[0].(string)
+
+		keys, _ := items[1].([]any)
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, key := range keys {
+				if k, ok := key.(string); ok {
+					args = append(args, k)
+				}
+			}
+			if _, err := s.client.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+func (s *Storage) CheckAndIncrementN(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*storage.ClientData, bool, error) {
+	reply, err := s.client.eval(ctx, fixedWindowScript, []string{s.key(clientID, "fw")},
+		micros(now),
+		durationMicros(windowDuration),
+		strconv.Itoa(maxRequests),
+		durationMicros(blockDuration),
+		strconv.Itoa(n),
+	)
+	values, err := ints(reply, err, 5)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if values[4] == 0 {
+		return nil, true, nil
+	}
+
+	return &storage.ClientData{
+		RequestCount: int(values[0]),
+		WindowStart:  fromMicros(values[1]),
+		BlockedUntil: fromMicros(values[2]),
+	}, values[3] == 1, nil
+}
+
+func (s *Storage) TakeToken(ctx context.Context, clientID string, now time.Time, refillRate float64, capacity int, n int) (*storage.TokenBucket, bool, error) {
+	reply, err := s.client.eval(ctx, tokenBucketScript, []string{s.key(clientID, "tb")},
+		micros(now),
+		strconv.FormatFloat(refillRate, 'g', -1, 64),
+		strconv.Itoa(capacity),
+		strconv.Itoa(n),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	items, ok := reply.([]any)
+	if !ok || len(items) != 3 {
+		return nil, false, errUnexpectedReply
+	}
+
+	encoded, _ := items[0].(string)
+	tokens, err := strconv.ParseFloat(encoded, 64)
+	if err != nil {
+		return nil, false, err
+	}
+	last, _ := items[1].(int64)
+	allowed, _ := items[2].(int64)
+
+	return &storage.TokenBucket{
+		Tokens:     tokens,
+		LastRefill: fromMicros(last),
+	}, allowed == 1, nil
+}
+
+func (s *Storage) SlidingWindowIncrement(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.WindowCounter, bool, error) {
+	reply, err := s.client.eval(ctx, slidingWindowScript, []string{s.key(clientID, "sw")},
+		micros(now),
+		durationMicros(windowDuration),
+		strconv.Itoa(maxRequests),
+		strconv.Itoa(n),
+	)
+	values, err := ints(reply, err, 4)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &storage.WindowCounter{
+		CurrentCount:  int(values[0]),
+		PreviousCount: int(values[1]),
+		WindowStart:   fromMicros(values[2]),
+	}, values[3] == 1, nil
+}
+
+func (s *Storage) AppendToLog(ctx context.Context, clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*storage.RequestLog, bool, error) {
+	reply, err := s.client.eval(ctx, slidingLogScript, []string{s.key(clientID, "sl"), s.key(clientID, "sl:seq")},
+		micros(now),
+		durationMicros(windowDuration),
+		strconv.Itoa(maxRequests),
+		strconv.Itoa(n),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return nil, false, errUnexpectedReply
+	}
+
+	allowed, _ := items[0].(int64)
+	log := &storage.RequestLog{
+		Timestamps: make([]time.Time, 0, len(items)-1),
+	}
+	for _, item := range items[1:] {
+		score, _ := item.(string)
+		value, err := strconv.ParseFloat(score, 64)
+		if err != nil {
+			return nil, false, err
+		}
+		log.Timestamps = append(log.Timestamps, time.UnixMicro(int64(value)))
+	}
+
+	return log, allowed == 1, nil
+}
+
+func (s *Storage) UpdateArrivalTime(ctx context.Context, clientID string, now time.Time, emissionInterval time.Duration, burst int, n int) (time.Time, bool, error) {
+	reply, err := s.client.eval(ctx, gcraScript, []string{s.key(clientID, "gcra")},
+		micros(now),
+		durationMicros(emissionInterval),
+		strconv.Itoa(burst),
+		strconv.Itoa(n),
+	)
+	values, err := ints(reply, err, 2)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return time.UnixMicro(values[0]), values[1] == 1, nil
+}
+
+func (s *Storage) ScheduleLeak(ctx context.Context, clientID string, now time.Time, leakInterval time.Duration, maxDelay time.Duration, n int) (time.Time, bool, error) {
+	reply, err := s.client.eval(ctx, leakyBucketScript, []string{s.key(clientID, "lb")},
+		micros(now),
+		durationMicros(leakInterval),
+		durationMicros(maxDelay),
+		strconv.Itoa(n),
+	)
+	values, err := ints(reply, err, 2)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return time.UnixMicro(values[0]), values[1] == 1, nil
+}
+
+func bulkStrings(reply any, size int) ([]*string, error) {
+	items, ok := reply.([]any)
+	if !ok || len(items) != size {
+		return nil, errUnexpectedReply
+	}
+
+	fields := make([]*string, size)
+	for i, item := range items {
+		if v, ok := item.(string); ok {
+			fields[i] = &v
+		}
+	}
+	return fields, nil
+}
+
+func parseInt(field *string) int64 {
+	if field == nil {
+		return 0
+	}
+	v, _ := strconv.ParseInt(*field, 10, 64)
+	return v
+}
+
+func ints(reply any, err error, size int) ([]int64, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := reply.([]any)
+	if !ok || len(items) != size {
+		return nil, errUnexpectedReply
+	}
+
+	values := make([]int64, size)
+	for i, item := range items {
+		if values[i], ok = item.(int64); !ok {
+			return nil, errUnexpectedReply
+		}
+	}
+	return values, nil
+}
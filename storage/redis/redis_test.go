@@ -0,0 +1,377 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+	"github.com/iramosg/devin-ai-ratelimiter/storage/storagetest"
+)
+
+var _ ratelimiter.StorageV2 = (*Storage)(nil)
+
+// newTestStorage connects to REDIS_ADDR when set and to an in-process fake
+// otherwise.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = newFakeServer(t).addr()
+	}
+
+	s := New(addr, WithKeyPrefix("ratelimit-test:"+strconv.FormatInt(time.Now().UnixNano(), 36)+":"))
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Clear(context.Background())
+		s.Close()
+	})
+
+	return s
+}
+
+func testNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func TestCheckAndIncrementN(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := testNow()
+
+	if _, exists, err := s.GetClientData(ctx, "client"); exists || err != nil {
+		t.Fatalf("Expected no data for a new client, got %v/%v", exists, err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		data, allowed, err := s.CheckAndIncrementN(ctx, "client", now, time.Minute, 3, time.Minute, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !allowed || data.RequestCount != i {
+			t.Fatalf("Request %d: expected allowed with count %d, got %v/%d", i, i, allowed, data.RequestCount)
+		}
+	}
+
+	data, _, _ := s.CheckAndIncrementN(ctx, "client", now, time.Minute, 3, time.Minute, 1)
+	if !data.BlockedUntil.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected block until %v, got %v", now.Add(time.Minute), data.BlockedUntil)
+	}
+
+	stored, exists, _ := s.GetClientData(ctx, "client")
+	if !exists || stored.RequestCount != 4 || !stored.WindowStart.Equal(now) || !stored.BlockedUntil.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected stored data to match, got %+v", stored)
+	}
+
+	if _, allowed, _ := s.CheckAndIncrementN(ctx, "client", now.Add(time.Second), time.Minute, 3, time.Minute, 1); allowed {
+		t.Error("Expected blocked client to be denied")
+	}
+
+	data, allowed, _ := s.CheckAndIncrementN(ctx, "client", now.Add(2*time.Minute), time.Minute, 3, time.Minute, 1)
+	if !allowed || data.RequestCount != 1 {
+		t.Errorf("Expected fresh window after block, got %v/%d", allowed, data.RequestCount)
+	}
+
+	data, _, _ = s.CheckAndIncrementN(ctx, "client", now.Add(2*time.Minute), time.Minute, 3, time.Minute, -1)
+	if data.RequestCount != 0 {
+		t.Errorf("Expected release to return count to 0, got %d", data.RequestCount)
+	}
+
+	if data, allowed, _ := s.CheckAndIncrementN(ctx, "unknown", now, time.Minute, 3, time.Minute, -1); data != nil || !allowed {
+		t.Errorf("Expected releasing an unknown client to be a no-op, got %+v/%v", data, allowed)
+	}
+}
+
+func TestTakeToken(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := testNow()
+
+	for i := 0; i < 5; i++ {
+		if _, allowed, _ := s.TakeToken(ctx, "client", now, 1, 5, 1); !allowed {
+			t.Fatalf("Request %d: expected token", i)
+		}
+	}
+
+	bucket, allowed, _ := s.TakeToken(ctx, "client", now, 1, 5, 1)
+	if allowed {
+		t.Error("Expected empty bucket to deny")
+	}
+	if bucket.Tokens != 0 {
+		t.Errorf("Expected 0 tokens, got %v", bucket.Tokens)
+	}
+
+	bucket, allowed, _ = s.TakeToken(ctx, "client", now.Add(1500*time.Millisecond), 1, 5, 1)
+	if !allowed {
+		t.Error("Expected refilled token")
+	}
+	if bucket.Tokens != 0.5 {
+		t.Errorf("Expected 0.5 tokens, got %v", bucket.Tokens)
+	}
+	if !bucket.LastRefill.Equal(now.Add(1500 * time.Millisecond)) {
+		t.Errorf("Expected LastRefill %v, got %v", now.Add(1500*time.Millisecond), bucket.LastRefill)
+	}
+}
+
+func TestSlidingWindowIncrement(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	start := testNow().Truncate(time.Minute)
+
+	for i := 0; i < 4; i++ {
+		if _, allowed, _ := s.SlidingWindowIncrement(ctx, "client", start, time.Minute, 4, 1); !allowed {
+			t.Fatalf("Request %d: expected allowed", i)
+		}
+	}
+	if _, allowed, _ := s.SlidingWindowIncrement(ctx, "client", start, time.Minute, 4, 1); allowed {
+		t.Error("Expected full window to deny")
+	}
+
+	counter, allowed, _ := s.SlidingWindowIncrement(ctx, "client", start.Add(90*time.Second), time.Minute, 4, 1)
+	if !allowed {
+		t.Error("Expected half-weighted previous window to allow")
+	}
+	if counter.PreviousCount != 4 || counter.CurrentCount != 1 {
+		t.Errorf("Expected previous 4 and current 1, got %d/%d", counter.PreviousCount, counter.CurrentCount)
+	}
+	if !counter.WindowStart.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected WindowStart %v, got %v", start.Add(time.Minute), counter.WindowStart)
+	}
+}
+
+func TestAppendToLog(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := testNow()
+
+	s.AppendToLog(ctx, "client", now, time.Minute, 3, 2)
+	log, allowed, _ := s.AppendToLog(ctx, "client", now.Add(time.Second), time.Minute, 3, 1)
+	if !allowed || len(log.Timestamps) != 3 {
+		t.Fatalf("Expected 3 entries, got %v/%d", allowed, len(log.Timestamps))
+	}
+	if !log.Timestamps[0].Equal(now) {
+		t.Errorf("Expected oldest %v, got %v", now, log.Timestamps[0])
+	}
+
+	if _, allowed, _ := s.AppendToLog(ctx, "client", now.Add(time.Second), time.Minute, 3, 1); allowed {
+		t.Error("Expected full log to deny")
+	}
+
+	log, allowed, _ = s.AppendToLog(ctx, "client", now.Add(time.Minute+time.Microsecond), time.Minute, 3, 1)
+	if !allowed || len(log.Timestamps) != 2 {
+		t.Errorf("Expected expired entries to be dropped, got %v/%d", allowed, len(log.Timestamps))
+	}
+
+	log, _, _ = s.AppendToLog(ctx, "client", now.Add(time.Minute+time.Microsecond), time.Minute, 3, -1)
+	if len(log.Timestamps) != 1 {
+		t.Errorf("Expected release to remove the newest entry, got %d", len(log.Timestamps))
+	}
+}
+
+func TestUpdateArrivalTime(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := testNow()
+
+	for i := 0; i < 2; i++ {
+		if _, allowed, _ := s.UpdateArrivalTime(ctx, "client", now, time.Second, 2, 1); !allowed {
+			t.Fatalf("Request %d: expected allowed", i)
+		}
+	}
+
+	tat, allowed, _ := s.UpdateArrivalTime(ctx, "client", now, time.Second, 2, 1)
+	if allowed {
+		t.Error("Expected burst to be exhausted")
+	}
+	if !tat.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("Expected TAT %v, got %v", now.Add(2*time.Second), tat)
+	}
+
+	if _, allowed, _ := s.UpdateArrivalTime(ctx, "client", now.Add(time.Second), time.Second, 2, 1); !allowed {
+		t.Error("Expected request after one interval to be allowed")
+	}
+}
+
+func TestScheduleLeak(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := testNow()
+
+	slot, _, _ := s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 1)
+	if !slot.Equal(now) {
+		t.Errorf("Expected first slot %v, got %v", now, slot)
+	}
+	slot, _, _ = s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 1)
+	if !slot.Equal(now.Add(time.Second)) {
+		t.Errorf("Expected second slot %v, got %v", now.Add(time.Second), slot)
+	}
+	s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 1)
+
+	if _, allowed, _ := s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 1); allowed {
+		t.Error("Expected full queue to deny")
+	}
+}
+
+func TestScheduleLeakCost(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := testNow()
+
+	s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 1)
+	s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 1)
+
+	if _, allowed, _ := s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 3); allowed {
+		t.Error("Expected a cost overflowing the queue to deny")
+	}
+	if _, allowed, _ := s.ScheduleLeak(ctx, "client", now, time.Second, 2*time.Second, 1); !allowed {
+		t.Error("Expected a cost fitting the queue to be allowed")
+	}
+}
+
+func TestDeleteClientAndClear(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := testNow()
+
+	s.CheckAndIncrementN(ctx, "a", now, time.Minute, 5, time.Minute, 1)
+	s.TakeToken(ctx, "a", now, 1, 5, 1)
+	s.AppendToLog(ctx, "a", now, time.Minute, 5, 1)
+	s.CheckAndIncrementN(ctx, "b", now, time.Minute, 5, time.Minute, 1)
+
+	if err := s.DeleteClient(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists, _ := s.GetClientData(ctx, "a"); exists {
+		t.Error("Expected client a to be deleted")
+	}
+	if _, exists, _ := s.GetClientData(ctx, "b"); !exists {
+		t.Error("Expected client b to remain")
+	}
+	bucket, _, _ := s.TakeToken(ctx, "a", now, 1, 5, 0)
+	if bucket.Tokens != 5 {
+		t.Errorf("Expected a fresh bucket after delete, got %v tokens", bucket.Tokens)
+	}
+
+	if err := s.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists, _ := s.GetClientData(ctx, "b"); exists {
+		t.Error("Expected client b to be cleared")
+	}
+}
+
+func TestKeyPrefixIsolation(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	a := New(server.addr(), WithKeyPrefix("a:"))
+	b := New(server.addr(), WithKeyPrefix("b:"))
+	defer a.Close()
+	defer b.Close()
+
+	a.CheckAndIncrementN(ctx, "client", testNow(), time.Minute, 5, time.Minute, 1)
+	if _, exists, _ := b.GetClientData(ctx, "client"); exists {
+		t.Error("Expected prefixes to isolate storages")
+	}
+
+	b.CheckAndIncrementN(ctx, "client", testNow(), time.Minute, 5, time.Minute, 1)
+	a.Clear(ctx)
+	if server.keyCount() != 1 {
+		t.Errorf("Expected Clear to leave other prefixes alone, got %d keys", server.keyCount())
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	s := New("127.0.0.1:1", WithDialTimeout(100*time.Millisecond))
+	defer s.Close()
+
+	if _, _, err := s.CheckAndIncrementN(context.Background(), "client", testNow(), time.Minute, 1, time.Minute, 1); err == nil {
+		t.Error("Expected an error when Redis is unreachable")
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	s := newTestStorage(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := s.TakeToken(ctx, "client", testNow(), 1, 5, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRateLimiterIntegration(t *testing.T) {
+	algorithms := []ratelimiter.Algorithm{
+		ratelimiter.FixedWindow,
+		ratelimiter.TokenBucket,
+		ratelimiter.SlidingWindow,
+		ratelimiter.SlidingLog,
+		ratelimiter.GCRA,
+	}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			limiter := ratelimiter.New(
+				ratelimiter.WithStorageV2(newTestStorage(t)),
+				ratelimiter.WithAlgorithm(algorithm),
+				ratelimiter.WithMaxRequests(3),
+				ratelimiter.WithWindowDuration(time.Minute),
+			)
+
+			for i := 0; i < 3; i++ {
+				result, err := limiter.AllowContext(context.Background(), "client")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !result.Allowed {
+					t.Fatalf("Request %d: expected allowed", i)
+				}
+			}
+			if limiter.Allow("client").Allowed {
+				t.Error("Expected fourth request to be denied")
+			}
+		})
+	}
+}
+
+func TestStatusWritesNothing(t *testing.T) {
+	algorithms := []ratelimiter.Algorithm{
+		ratelimiter.FixedWindow,
+		ratelimiter.TokenBucket,
+		ratelimiter.SlidingWindow,
+		ratelimiter.SlidingLog,
+		ratelimiter.GCRA,
+		ratelimiter.LeakyBucket,
+	}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			s := newTestStorage(t)
+			limiter := ratelimiter.New(ratelimiter.WithStorageV2(s), ratelimiter.WithAlgorithm(algorithm))
+
+			if _, err := limiter.StatusContext(context.Background(), "unknown"); err != nil {
+				t.Fatal(err)
+			}
+
+			reply, err := s.client.do(context.Background(), append([]string{"EXISTS"}, s.keys("unknown")...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reply != int64(0) {
+				t.Errorf("Expected Status to create no keys, got %v", reply)
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.RunConformance(t, func(t *testing.T) ratelimiter.StorageV2 {
+		return newTestStorage(t)
+	})
+}
@@ -0,0 +1,247 @@
+package redis
+
+// All timestamps and durations are passed to the scripts in microseconds since
+// the Unix epoch. Integers are written with %.0f so that Lua's default number
+// formatting never turns them into exponent notation. A zero n only reads the
+// client's state: the scripts then write nothing and create no keys.
+
+var fixedWindowScript = newScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local max = tonumber(ARGV[3])
+local block = tonumber(ARGV[4])
+local n = tonumber(ARGV[5])
+
+local data = redis.call('HMGET', KEYS[1], 'count', 'start', 'blocked')
+local count = tonumber(data[1])
+local start = tonumber(data[2])
+local blocked = tonumber(data[3]) or 0
+local exists = count ~= nil
+
+if n < 0 then
+	if not exists then
+		return {0, 0, 0, 1, 0}
+	end
+	if now - start < window then
+		count = math.max(0, count + n)
+		redis.call('HSET', KEYS[1], 'count', string.format('%.0f', count))
+	end
+	return {count, start, blocked, 1, 1}
+end
+
+if exists and blocked ~= 0 and now < blocked then
+	return {count, start, blocked, 0, 1}
+end
+
+if not exists or (blocked ~= 0 and now > blocked) or now - start >= window then
+	count = 0
+	start = now
+	blocked = 0
+end
+
+if n == 0 then
+	return {count, start, blocked, 1, 1}
+end
+if count < max and count + n > max then
+	return {count, start, blocked, 0, 1}
+end
+
+count = count + n
+if count > max then
+	blocked = now + block
+end
+
+redis.call('HSET', KEYS[1],
+	'count', string.format('%.0f', count),
+	'start', string.format('%.0f', start),
+	'blocked', string.format('%.0f', blocked))
+redis.call('PEXPIREAT', KEYS[1], string.format('%.0f', math.ceil(math.max(start + window, blocked) / 1000) + 1))
+
+return {count, start, blocked, 1, 1}
+`)
+
+var tokenBucketScript = newScript(`
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(data[1])
+local last = tonumber(data[2])
+if tokens == nil then
+	tokens = capacity
+	last = now
+end
+
+if now > last then
+	tokens = math.min(capacity, tokens + (now - last) / 1e6 * rate)
+	last = now
+end
+
+local allowed = 0
+if tokens >= n then
+	tokens = math.min(tokens - n, capacity)
+	allowed = 1
+end
+
+local encoded = string.format('%.17g', tokens)
+if n == 0 then
+	return {encoded, last, allowed}
+end
+redis.call('HSET', KEYS[1], 'tokens', encoded, 'last', string.format('%.0f', last))
+if rate > 0 then
+	local full = now + (capacity - tokens) / rate * 1e6
+	redis.call('PEXPIREAT', KEYS[1], string.format('%.0f', math.ceil(full / 1000) + 1))
+end
+
+return {encoded, last, allowed}
+`)
+
+var slidingWindowScript = newScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local max = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+local start = now - (now % window)
+
+local data = redis.call('HMGET', KEYS[1], 'current', 'previous', 'start')
+local current = tonumber(data[1]) or 0
+local previous = tonumber(data[2]) or 0
+local windowStart = tonumber(data[3]) or start
+
+if start > windowStart then
+	if start - windowStart == window then
+		previous = current
+	else
+		previous = 0
+	end
+	current = 0
+	windowStart = start
+end
+
+local weight = math.max(0, 1 - (now - windowStart) / window)
+local allowed = 0
+if n < 0 or previous * weight + current + n <= max then
+	current = math.max(0, current + n)
+	allowed = 1
+end
+if n == 0 then
+	return {current, previous, windowStart, allowed}
+end
+
+redis.call('HSET', KEYS[1],
+	'current', string.format('%.0f', current),
+	'previous', string.format('%.0f', previous),
+	'start', string.format('%.0f', windowStart))
+redis.call('PEXPIREAT', KEYS[1], string.format('%.0f', math.ceil((windowStart + 2 * window) / 1000) + 1))
+
+return {current, previous, windowStart, allowed}
+`)
+
+var slidingLogScript = newScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local max = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+
+if n == 0 then
+	local entries = redis.call('ZRANGEBYSCORE', KEYS[1], '(' .. string.format('%.0f', now - window), '+inf', 'WITHSCORES')
+	local result = {0}
+	if #entries / 2 <= max then
+		result[1] = 1
+	end
+	for i = 2, #entries, 2 do
+		result[#result + 1] = entries[i]
+	end
+	return result
+end
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.0f', now - window))
+local count = redis.call('ZCARD', KEYS[1])
+
+local allowed = 0
+if n < 0 then
+	local remove = math.min(count, -n)
+	if remove > 0 then
+		redis.call('ZREMRANGEBYRANK', KEYS[1], -remove, -1)
+	end
+	allowed = 1
+elseif count + n <= max then
+	for i = 1, n do
+		local seq = redis.call('INCR', KEYS[2])
+		redis.call('ZADD', KEYS[1], string.format('%.0f', now), seq)
+	end
+	allowed = 1
+end
+
+local entries = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+if #entries == 0 then
+	redis.call('DEL', KEYS[1], KEYS[2])
+else
+	local expireAt = string.format('%.0f', math.ceil((tonumber(entries[#entries]) + window) / 1000) + 1)
+	redis.call('PEXPIREAT', KEYS[1], expireAt)
+	redis.call('PEXPIREAT', KEYS[2], expireAt)
+end
+
+local result = {allowed}
+for i = 2, #entries, 2 do
+	result[#result + 1] = entries[i]
+end
+return result
+`)
+
+var gcraScript = newScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+
+local newTAT = tat + interval * n
+if n >= 0 and now < newTAT - interval * burst then
+	return {tat, 0}
+end
+if n == 0 then
+	return {tat, 1}
+end
+if newTAT < now then
+	newTAT = now
+end
+
+redis.call('SET', KEYS[1], string.format('%.0f', newTAT))
+redis.call('PEXPIREAT', KEYS[1], string.format('%.0f', math.ceil(newTAT / 1000) + 1))
+return {newTAT, 1}
+`)
+
+var leakyBucketScript = newScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local maxDelay = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+
+local slot = tonumber(redis.call('GET', KEYS[1]))
+if slot == nil or slot < now then
+	slot = now
+end
+
+if n >= 0 and slot + interval * math.max(n - 1, 0) - now > maxDelay then
+	return {slot, 0}
+end
+if n == 0 then
+	return {slot, 1}
+end
+
+local tail = math.max(now, slot + interval * n)
+redis.call('SET', KEYS[1], string.format('%.0f', tail))
+redis.call('PEXPIREAT', KEYS[1], string.format('%.0f', math.ceil(tail / 1000) + 1))
+
+if n < 0 then
+	return {tail, 1}
+end
+return {slot, 1}
+`)
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// ShardedMemoryStorage spreads clients over independent MemoryStorage shards,
+// keyed by a hash of the client ID, so concurrent requests from different clients
+// rarely contend for the same mutex.
+type ShardedMemoryStorage struct {
+	seed   maphash.Seed
+	shards []*MemoryStorage
+
+	statsMu sync.Mutex
+	stats   JanitorStats
+	janitor janitor
+}
+
+func NewShardedMemoryStorage(shards int) *ShardedMemoryStorage {
+	if shards < 1 {
+		shards = 1
+	}
+
+	s := &ShardedMemoryStorage{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*MemoryStorage, shards),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewMemoryStorage()
+	}
+	return s
+}
+
+func (s *ShardedMemoryStorage) shard(clientID string) *MemoryStorage {
+	return s.shards[maphash.String(s.seed, clientID)%uint64(len(s.shards))]
+}
+
+func (s *ShardedMemoryStorage) GetClientData(clientID string) (*ClientData, bool) {
+	return s.shard(clientID).GetClientData(clientID)
+}
+
+func (s *ShardedMemoryStorage) SetClientData(clientID string, data *ClientData) {
+	s.shard(clientID).SetClientData(clientID, data)
+}
+
+func (s *ShardedMemoryStorage) IncrementRequestCount(clientID string) int {
+	return s.shard(clientID).IncrementRequestCount(clientID)
+}
+
+func (s *ShardedMemoryStorage) ResetWindow(clientID string, windowStart time.Time) {
+	s.shard(clientID).ResetWindow(clientID, windowStart)
+}
+
+func (s *ShardedMemoryStorage) BlockClient(clientID string, blockedUntil time.Time) {
+	s.shard(clientID).BlockClient(clientID, blockedUntil)
+}
+
+func (s *ShardedMemoryStorage) DeleteClient(clientID string) {
+	s.shard(clientID).DeleteClient(clientID)
+}
+
+func (s *ShardedMemoryStorage) Clear() {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+}
+
+func (s *ShardedMemoryStorage) CheckAndIncrement(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration) (*ClientData, bool) {
+	return s.shard(clientID).CheckAndIncrement(clientID, now, windowDuration, maxRequests, blockDuration)
+}
+
+func (s *ShardedMemoryStorage) CheckAndIncrementN(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration, n int) (*ClientData, bool) {
+	return s.shard(clientID).CheckAndIncrementN(clientID, now, windowDuration, maxRequests, blockDuration, n)
+}
+
+func (s *ShardedMemoryStorage) TakeToken(clientID string, now time.Time, refillRate float64, capacity int, n int) (*TokenBucket, bool) {
+	return s.shard(clientID).TakeToken(clientID, now, refillRate, capacity, n)
+}
+
+func (s *ShardedMemoryStorage) SlidingWindowIncrement(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*WindowCounter, bool) {
+	return s.shard(clientID).SlidingWindowIncrement(clientID, now, windowDuration, maxRequests, n)
+}
+
+func (s *ShardedMemoryStorage) AppendToLog(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*RequestLog, bool) {
+	return s.shard(clientID).AppendToLog(clientID, now, windowDuration, maxRequests, n)
+}
+
+func (s *ShardedMemoryStorage) UpdateArrivalTime(clientID string, now time.Time, emissionInterval time.Duration, burst int, n int) (time.Time, bool) {
+	return s.shard(clientID).UpdateArrivalTime(clientID, now, emissionInterval, burst, n)
+}
+
+func (s *ShardedMemoryStorage) ScheduleLeak(clientID string, now time.Time, leakInterval time.Duration, maxDelay time.Duration, n int) (time.Time, bool) {
+	return s.shard(clientID).ScheduleLeak(clientID, now, leakInterval, maxDelay, n)
+}
+
+// Sweep evicts expired clients from every shard and returns how many were
+// evicted. It counts as a single sweep in JanitorStats.
+func (s *ShardedMemoryStorage) Sweep(now time.Time) int {
+	evicted := 0
+	for _, shard := range s.shards {
+		evicted += shard.Sweep(now)
+	}
+
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	s.stats.Sweeps++
+	s.stats.Evicted += uint64(evicted)
+	s.stats.LastSweep = now
+	s.stats.LastEvicted = evicted
+
+	return evicted
+}
+
+// StartJanitor runs Sweep every interval in a single background goroutine until
+// Close is called. Calling it while the janitor is already running has no
+// effect. Like time.NewTicker, it panics if interval is not positive.
+func (s *ShardedMemoryStorage) StartJanitor(interval time.Duration) {
+	s.janitor.start(interval, s.Sweep)
+}
+
+func (s *ShardedMemoryStorage) Close() error {
+	s.janitor.close()
+	return nil
+}
+
+func (s *ShardedMemoryStorage) JanitorStats() JanitorStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	return s.stats
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewShardedMemoryStorage(t *testing.T) {
+	storage := NewShardedMemoryStorage(0)
+	if len(storage.shards) != 1 {
+		t.Errorf("Expected at least one shard, got %d", len(storage.shards))
+	}
+
+	storage = NewShardedMemoryStorage(16)
+	if len(storage.shards) != 16 {
+		t.Errorf("Expected 16 shards, got %d", len(storage.shards))
+	}
+}
+
+func TestShardedMemoryStorage_RoutesConsistently(t *testing.T) {
+	storage := NewShardedMemoryStorage(8)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		clientID := fmt.Sprintf("client-%d", i)
+		storage.CheckAndIncrement(clientID, now, time.Minute, 10, time.Minute)
+		storage.CheckAndIncrement(clientID, now, time.Minute, 10, time.Minute)
+	}
+
+	for i := 0; i < 100; i++ {
+		data, exists := storage.GetClientData(fmt.Sprintf("client-%d", i))
+		if !exists {
+			t.Fatalf("Expected client-%d to exist", i)
+		}
+		if data.RequestCount != 2 {
+			t.Errorf("Expected RequestCount 2 for client-%d, got %d", i, data.RequestCount)
+		}
+	}
+
+	used := 0
+	for _, shard := range storage.shards {
+		if len(shard.clients) > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("Expected clients to be spread across shards, used %d", used)
+	}
+}
+
+func TestShardedMemoryStorage_DeleteAndClear(t *testing.T) {
+	storage := NewShardedMemoryStorage(4)
+	now := time.Now()
+
+	storage.CheckAndIncrement("client1", now, time.Minute, 10, time.Minute)
+	storage.CheckAndIncrement("client2", now, time.Minute, 10, time.Minute)
+
+	storage.DeleteClient("client1")
+	if _, exists := storage.GetClientData("client1"); exists {
+		t.Error("Expected client1 to be deleted")
+	}
+
+	storage.Clear()
+	if _, exists := storage.GetClientData("client2"); exists {
+		t.Error("Expected client2 to be cleared")
+	}
+}
+
+func TestShardedMemoryStorage_ConcurrentAtomicity(t *testing.T) {
+	storage := NewShardedMemoryStorage(8)
+	now := time.Now()
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := storage.TakeToken("shared", now, 0, 50, 1); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 50 {
+		t.Errorf("Expected exactly 50 tokens granted, got %d", allowed.Load())
+	}
+}
+
+func TestShardedMemoryStorage_Sweep(t *testing.T) {
+	storage := NewShardedMemoryStorage(4)
+	now := time.Now()
+
+	for i := 0; i < 20; i++ {
+		storage.CheckAndIncrement(fmt.Sprintf("client-%d", i), now, time.Minute, 10, time.Minute)
+	}
+
+	if evicted := storage.Sweep(now.Add(time.Minute)); evicted != 20 {
+		t.Errorf("Expected 20 evicted clients, got %d", evicted)
+	}
+	if stats := storage.JanitorStats(); stats.Evicted != 20 || stats.Sweeps != 1 || stats.LastEvicted != 20 {
+		t.Errorf("Expected one sweep evicting 20 clients, got %+v", stats)
+	}
+}
+
+type checkAndIncrementer interface {
+	CheckAndIncrement(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, blockDuration time.Duration) (*ClientData, bool)
+}
+
+// Run with -cpu=1,2,4,8 to compare how throughput scales with GOMAXPROCS.
+func benchmarkCheckAndIncrement(b *testing.B, storage checkAndIncrementer) {
+	clientIDs := make([]string, 1024)
+	for i := range clientIDs {
+		clientIDs[i] = fmt.Sprintf("client-%d", i)
+	}
+	now := time.Now()
+
+	var next atomic.Uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := next.Add(1) * 7919
+		for pb.Next() {
+			i++
+			storage.CheckAndIncrement(clientIDs[i%uint64(len(clientIDs))], now, time.Minute, 1<<30, time.Minute)
+		}
+	})
+}
+
+func BenchmarkMemoryStorage_CheckAndIncrement(b *testing.B) {
+	benchmarkCheckAndIncrement(b, NewMemoryStorage())
+}
+
+func BenchmarkShardedMemoryStorage_CheckAndIncrement(b *testing.B) {
+	benchmarkCheckAndIncrement(b, NewShardedMemoryStorage(4*runtime.GOMAXPROCS(0)))
+}
+
+func TestShardedMemoryStorage_StartJanitor(t *testing.T) {
+	storage := NewShardedMemoryStorage(8)
+	defer storage.Close()
+
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		storage.CheckAndIncrement(fmt.Sprintf("client-%d", i), now, 10*time.Millisecond, 10, 10*time.Millisecond)
+	}
+
+	storage.StartJanitor(5 * time.Millisecond)
+	storage.StartJanitor(5 * time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && storage.JanitorStats().Evicted < 20 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	storage.Close()
+
+	stats := storage.JanitorStats()
+	if stats.Evicted != 20 {
+		t.Fatalf("Expected 20 evicted clients, got %d", stats.Evicted)
+	}
+	if stats.LastEvicted > 20 {
+		t.Errorf("Expected LastEvicted to cover a single sweep, got %d", stats.LastEvicted)
+	}
+
+	var shardSweeps uint64
+	for _, shard := range storage.shards {
+		shardSweeps += shard.JanitorStats().Sweeps
+	}
+	if shardSweeps != stats.Sweeps*uint64(len(storage.shards)) {
+		t.Errorf("Expected every tick to sweep all %d shards once, got %d shard sweeps for %d sweeps", len(storage.shards), shardSweeps, stats.Sweeps)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"time"
+)
+
+type RequestLog struct {
+	Timestamps []time.Time
+}
+
+// Oldest returns the earliest timestamp still in the log, or the zero time if it is empty.
+func (l *RequestLog) Oldest() time.Time {
+	if len(l.Timestamps) == 0 {
+		return time.Time{}
+	}
+	return l.Timestamps[0]
+}
+
+// AppendToLog drops timestamps older than windowDuration and records n entries at
+// now if they fit within maxRequests. Denied requests are never recorded, so a
+// client's log holds at most maxRequests entries. A negative n removes the most
+// recent entries. A zero n only reports the log without storing it.
+func (s *MemoryStorage) AppendToLog(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*RequestLog, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, exists := s.logs[clientID]
+	if !exists {
+		log = &RequestLog{}
+	}
+
+	cutoff := now.Add(-windowDuration)
+	expired := 0
+	for expired < len(log.Timestamps) && !log.Timestamps[expired].After(cutoff) {
+		expired++
+	}
+
+	if n == 0 {
+		logCopy := &RequestLog{
+			Timestamps: make([]time.Time, len(log.Timestamps)-expired),
+		}
+		copy(logCopy.Timestamps, log.Timestamps[expired:])
+		return logCopy, len(logCopy.Timestamps) <= maxRequests
+	}
+	s.logs[clientID] = log
+
+	if expired > 0 {
+		log.Timestamps = append(make([]time.Time, 0, maxRequests), log.Timestamps[expired:]...)
+	}
+
+	if n < 0 {
+		log.Timestamps = log.Timestamps[:max(0, len(log.Timestamps)+n)]
+	}
+
+	allowed := len(log.Timestamps)+n <= maxRequests
+	if allowed {
+		for i := 0; i < n; i++ {
+			log.Timestamps = append(log.Timestamps, now)
+		}
+	}
+
+	if len(log.Timestamps) > 0 {
+		s.touch(clientID, log.Timestamps[len(log.Timestamps)-1].Add(windowDuration))
+	} else {
+		s.touch(clientID, now)
+	}
+	s.use(clientID, now, time.Time{})
+
+	logCopy := &RequestLog{
+		Timestamps: make([]time.Time, len(log.Timestamps)),
+	}
+	copy(logCopy.Timestamps, log.Timestamps)
+	return logCopy, allowed
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendToLog_ExactLimit(t *testing.T) {
+	storage := NewMemoryStorage()
+	start := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		if _, allowed := storage.AppendToLog("test-client", start.Add(time.Duration(i)*time.Minute), time.Hour, 5, 1); !allowed {
+			t.Errorf("Request %d should be allowed", i+1)
+		}
+	}
+
+	log, allowed := storage.AppendToLog("test-client", start.Add(59*time.Minute), time.Hour, 5, 1)
+	if allowed {
+		t.Error("Sixth request within the hour should not be allowed")
+	}
+	if len(log.Timestamps) != 5 {
+		t.Errorf("Expected 5 logged requests, got %d", len(log.Timestamps))
+	}
+	if !log.Oldest().Equal(start) {
+		t.Errorf("Expected oldest entry %v, got %v", start, log.Oldest())
+	}
+}
+
+func TestAppendToLog_TrimsExpiredEntries(t *testing.T) {
+	storage := NewMemoryStorage()
+	start := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	storage.AppendToLog("test-client", start, time.Hour, 2, 1)
+	storage.AppendToLog("test-client", start.Add(30*time.Minute), time.Hour, 2, 1)
+
+	log, allowed := storage.AppendToLog("test-client", start.Add(time.Hour), time.Hour, 2, 1)
+	if !allowed {
+		t.Error("Request should be allowed once the oldest entry expires")
+	}
+	if len(log.Timestamps) != 2 {
+		t.Errorf("Expected 2 logged requests, got %d", len(log.Timestamps))
+	}
+	if !log.Oldest().Equal(start.Add(30 * time.Minute)) {
+		t.Errorf("Expected expired entry to be trimmed, oldest is %v", log.Oldest())
+	}
+}
+
+func TestAppendToLog_BoundedByMaxRequests(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		storage.AppendToLog("test-client", now, time.Hour, 3, 1)
+	}
+
+	if got := len(storage.logs["test-client"].Timestamps); got != 3 {
+		t.Errorf("Expected log to hold at most 3 entries, got %d", got)
+	}
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"time"
+)
+
+type WindowCounter struct {
+	CurrentCount  int
+	PreviousCount int
+	WindowStart   time.Time
+}
+
+// Estimate returns the weighted request count for the sliding window ending at now.
+func (c *WindowCounter) Estimate(now time.Time, windowDuration time.Duration) float64 {
+	weight := 1 - float64(now.Sub(c.WindowStart))/float64(windowDuration)
+	if weight < 0 {
+		weight = 0
+	}
+	return float64(c.PreviousCount)*weight + float64(c.CurrentCount)
+}
+
+// SlidingWindowIncrement counts n requests in the current window if the weighted
+// estimate allows it. A negative n releases requests counted in the current window.
+// A zero n only reports the counter without storing it.
+func (s *MemoryStorage) SlidingWindowIncrement(clientID string, now time.Time, windowDuration time.Duration, maxRequests int, n int) (*WindowCounter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	windowStart := now.Truncate(windowDuration)
+
+	counter := &WindowCounter{WindowStart: windowStart}
+	if stored, exists := s.windows[clientID]; exists {
+		*counter = *stored
+	}
+
+	if windowStart.After(counter.WindowStart) {
+		if windowStart.Sub(counter.WindowStart) == windowDuration {
+			counter.PreviousCount = counter.CurrentCount
+		} else {
+			counter.PreviousCount = 0
+		}
+		counter.CurrentCount = 0
+		counter.WindowStart = windowStart
+	}
+
+	allowed := n < 0 || counter.Estimate(now, windowDuration)+float64(n) <= float64(maxRequests)
+	if allowed {
+		counter.CurrentCount = max(0, counter.CurrentCount+n)
+	}
+	if n == 0 {
+		return counter, allowed
+	}
+	s.windows[clientID] = counter
+
+	s.touch(clientID, counter.WindowStart.Add(2*windowDuration))
+	s.use(clientID, now, time.Time{})
+
+	counterCopy := &WindowCounter{
+		CurrentCount:  counter.CurrentCount,
+		PreviousCount: counter.PreviousCount,
+		WindowStart:   counter.WindowStart,
+	}
+	return counterCopy, allowed
+}
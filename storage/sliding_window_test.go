@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowIncrement_WithinLimit(t *testing.T) {
+	storage := NewMemoryStorage()
+	windowStart := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		counter, allowed := storage.SlidingWindowIncrement("test-client", windowStart.Add(time.Second), time.Minute, 3, 1)
+		if !allowed {
+			t.Errorf("Request %d should be allowed", i)
+		}
+		if counter.CurrentCount != i {
+			t.Errorf("Expected CurrentCount %d, got %d", i, counter.CurrentCount)
+		}
+	}
+
+	counter, allowed := storage.SlidingWindowIncrement("test-client", windowStart.Add(2*time.Second), time.Minute, 3, 1)
+	if allowed {
+		t.Error("Request exceeding limit should not be allowed")
+	}
+	if counter.CurrentCount != 3 {
+		t.Errorf("Denied request should not be counted, got %d", counter.CurrentCount)
+	}
+}
+
+func TestSlidingWindowIncrement_WeightsPreviousWindow(t *testing.T) {
+	storage := NewMemoryStorage()
+	windowStart := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		storage.SlidingWindowIncrement("test-client", windowStart.Add(59*time.Second), time.Minute, 10, 1)
+	}
+
+	counter, allowed := storage.SlidingWindowIncrement("test-client", windowStart.Add(time.Minute), time.Minute, 10, 1)
+	if allowed {
+		t.Error("Request at the window boundary should be denied by the previous window's weight")
+	}
+	if counter.PreviousCount != 10 {
+		t.Errorf("Expected PreviousCount 10, got %d", counter.PreviousCount)
+	}
+
+	counter, allowed = storage.SlidingWindowIncrement("test-client", windowStart.Add(90*time.Second), time.Minute, 10, 1)
+	if !allowed {
+		t.Error("Request halfway through the next window should be allowed")
+	}
+	if estimate := counter.Estimate(windowStart.Add(90*time.Second), time.Minute); estimate != 6 {
+		t.Errorf("Expected estimate 6, got %v", estimate)
+	}
+}
+
+func TestSlidingWindowIncrement_DropsStaleWindows(t *testing.T) {
+	storage := NewMemoryStorage()
+	windowStart := time.Date(2025, 2, 6, 14, 0, 0, 0, time.UTC)
+
+	storage.SlidingWindowIncrement("test-client", windowStart, time.Minute, 10, 1)
+
+	counter, _ := storage.SlidingWindowIncrement("test-client", windowStart.Add(3*time.Minute), time.Minute, 10, 1)
+	if counter.PreviousCount != 0 {
+		t.Errorf("Expected PreviousCount 0 after an idle window, got %d", counter.PreviousCount)
+	}
+	if counter.CurrentCount != 1 {
+		t.Errorf("Expected CurrentCount 1, got %d", counter.CurrentCount)
+	}
+}
@@ -0,0 +1,633 @@
+// Package storagetest provides a conformance suite for ratelimiter storage
+// backends.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+)
+
+// Factory returns an empty storage for a single test. Storages implementing the
+// older ratelimiter.Storage interface can be wrapped with ratelimiter.AdaptStorage.
+type Factory func(t *testing.T) ratelimiter.StorageV2
+
+const (
+	window   = time.Minute
+	block    = 5 * time.Minute
+	maxCount = 3
+)
+
+// RunConformance checks that storages returned by factory implement the
+// semantics RateLimiter relies on for every algorithm, including releases
+// (negative n) and zero-cost reads. Subtests for algorithms and costs the
+// storage reports as unsupported, with ratelimiter.ErrUnsupported or
+// ratelimiter.ErrUnsupportedCost, are skipped; this is how an adapted Storage
+// without the optional interfaces is tested. Timestamps are derived from the current time
+// and truncated to microseconds, so backends may store them at that precision
+// and may expire keys based on the wall clock.
+func RunConformance(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s ratelimiter.StorageV2)
+	}{
+		{"UnknownClient", testUnknownClient},
+		{"CountsWithinWindow", testCountsWithinWindow},
+		{"Cost", testCost},
+		{"WindowReset", testWindowReset},
+		{"BlocksWhenExceeded", testBlocksWhenExceeded},
+		{"BlockExpires", testBlockExpires},
+		{"Release", testRelease},
+		{"ClientsAreIndependent", testClientsAreIndependent},
+		{"ConcurrentAtomicity", testConcurrentAtomicity},
+		{"DeleteClient", testDeleteClient},
+		{"Clear", testClear},
+		{"ZeroCostIsRead", testZeroCostIsRead},
+		{"TokenBucket", testTokenBucket},
+		{"TokenBucketRelease", testTokenBucketRelease},
+		{"SlidingWindow", testSlidingWindow},
+		{"SlidingWindowRelease", testSlidingWindowRelease},
+		{"SlidingLog", testSlidingLog},
+		{"SlidingLogRelease", testSlidingLogRelease},
+		{"GCRA", testGCRA},
+		{"GCRARelease", testGCRARelease},
+		{"LeakyBucket", testLeakyBucket},
+		{"LeakyBucketRelease", testLeakyBucketRelease},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, factory(t))
+		})
+	}
+}
+
+// skipUnsupported skips the test if err reports an operation the storage does
+// not implement.
+func skipUnsupported(t *testing.T, err error) {
+	t.Helper()
+
+	if errors.Is(err, ratelimiter.ErrUnsupported) || errors.Is(err, ratelimiter.ErrUnsupportedCost) {
+		t.Skipf("Storage does not support this operation: %v", err)
+	}
+}
+
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func check(t *testing.T, s ratelimiter.StorageV2, clientID string, at time.Time, n int) (int, time.Time, time.Time, bool) {
+	t.Helper()
+
+	data, allowed, err := s.CheckAndIncrementN(context.Background(), clientID, at, window, maxCount, block, n)
+	skipUnsupported(t, err)
+	if err != nil {
+		t.Fatalf("CheckAndIncrementN: %v", err)
+	}
+	if data == nil {
+		t.Fatal("CheckAndIncrementN returned nil data")
+	}
+	return data.RequestCount, data.WindowStart, data.BlockedUntil, allowed
+}
+
+func exists(t *testing.T, s ratelimiter.StorageV2, clientID string) bool {
+	t.Helper()
+
+	_, ok, err := s.GetClientData(context.Background(), clientID)
+	if err != nil {
+		t.Fatalf("GetClientData: %v", err)
+	}
+	return ok
+}
+
+func testUnknownClient(t *testing.T, s ratelimiter.StorageV2) {
+	data, ok, err := s.GetClientData(context.Background(), "client")
+	if err != nil {
+		t.Fatalf("GetClientData: %v", err)
+	}
+	if ok || data != nil {
+		t.Errorf("Expected no data for an unknown client, got %+v", data)
+	}
+}
+
+func testCountsWithinWindow(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	for i := 1; i <= maxCount; i++ {
+		count, windowStart, blockedUntil, allowed := check(t, s, "client", start.Add(time.Duration(i)*time.Second), 1)
+		if !allowed {
+			t.Fatalf("Request %d: expected allowed", i)
+		}
+		if count != i {
+			t.Errorf("Request %d: expected count %d, got %d", i, i, count)
+		}
+		if !windowStart.Equal(start.Add(time.Second)) {
+			t.Errorf("Request %d: expected window to start at the first request, got %v", i, windowStart)
+		}
+		if !blockedUntil.IsZero() {
+			t.Errorf("Request %d: expected no block, got %v", i, blockedUntil)
+		}
+	}
+
+	data, ok, _ := s.GetClientData(context.Background(), "client")
+	if !ok || data.RequestCount != maxCount {
+		t.Errorf("Expected GetClientData to report %d requests, got %+v", maxCount, data)
+	}
+}
+
+func testCost(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	count, _, _, _ := check(t, s, "client", start, 2)
+	if count != 2 {
+		t.Errorf("Expected a cost of 2 to count twice, got %d", count)
+	}
+
+	count, _, blockedUntil, allowed := check(t, s, "client", start, 2)
+	if allowed || count != 2 || !blockedUntil.IsZero() {
+		t.Errorf("Expected a cost that does not fit to be denied without charge or block, got %v/%d/%v", allowed, count, blockedUntil)
+	}
+
+	check(t, s, "client", start, 1)
+	count, _, blockedUntil, _ = check(t, s, "client", start, 2)
+	if count != maxCount+2 {
+		t.Errorf("Expected a request after the quota is used up to be counted, got %d", count)
+	}
+	if !blockedUntil.Equal(start.Add(block)) {
+		t.Errorf("Expected it to block until %v, got %v", start.Add(block), blockedUntil)
+	}
+}
+
+func testWindowReset(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	check(t, s, "client", start, 1)
+	check(t, s, "client", start, 1)
+
+	count, windowStart, _, allowed := check(t, s, "client", start.Add(window-time.Microsecond), 1)
+	if count != 3 || !windowStart.Equal(start) {
+		t.Errorf("Expected the window to still be open just before it ends, got count %d starting %v", count, windowStart)
+	}
+	if !allowed {
+		t.Error("Expected request within the window to be allowed")
+	}
+
+	count, windowStart, _, allowed = check(t, s, "client", start.Add(window), 1)
+	if !allowed || count != 1 {
+		t.Errorf("Expected a fresh window once the window has elapsed, got %v/%d", allowed, count)
+	}
+	if !windowStart.Equal(start.Add(window)) {
+		t.Errorf("Expected new window to start at %v, got %v", start.Add(window), windowStart)
+	}
+}
+
+func testBlocksWhenExceeded(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	for i := 0; i < maxCount; i++ {
+		check(t, s, "client", start, 1)
+	}
+
+	count, _, blockedUntil, _ := check(t, s, "client", start, 1)
+	if count != maxCount+1 {
+		t.Errorf("Expected the exceeding request to be counted, got %d", count)
+	}
+	if !blockedUntil.Equal(start.Add(block)) {
+		t.Errorf("Expected block until %v, got %v", start.Add(block), blockedUntil)
+	}
+
+	count, _, blockedUntil, allowed := check(t, s, "client", start.Add(block/2), 1)
+	if allowed {
+		t.Error("Expected blocked client to be denied")
+	}
+	if count != maxCount+1 {
+		t.Errorf("Expected denied requests not to be counted, got %d", count)
+	}
+	if !blockedUntil.Equal(start.Add(block)) {
+		t.Errorf("Expected block to be unchanged, got %v", blockedUntil)
+	}
+
+	if _, _, _, allowed := check(t, s, "client", start.Add(window+time.Second), 1); allowed {
+		t.Error("Expected block to outlast the window")
+	}
+}
+
+func testBlockExpires(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	for i := 0; i <= maxCount; i++ {
+		check(t, s, "client", start, 1)
+	}
+
+	count, windowStart, blockedUntil, allowed := check(t, s, "client", start.Add(block+time.Second), 1)
+	if !allowed || count != 1 {
+		t.Errorf("Expected a fresh window after the block, got %v/%d", allowed, count)
+	}
+	if !windowStart.Equal(start.Add(block + time.Second)) {
+		t.Errorf("Expected new window to start at %v, got %v", start.Add(block+time.Second), windowStart)
+	}
+	if !blockedUntil.IsZero() {
+		t.Errorf("Expected block to be cleared, got %v", blockedUntil)
+	}
+}
+
+func testRelease(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	data, allowed, err := s.CheckAndIncrementN(context.Background(), "unknown", start, window, maxCount, block, -1)
+	skipUnsupported(t, err)
+	if err != nil {
+		t.Fatalf("CheckAndIncrementN: %v", err)
+	}
+	if !allowed || data != nil {
+		t.Errorf("Expected releasing an unknown client to be a no-op, got %+v/%v", data, allowed)
+	}
+
+	check(t, s, "client", start, 2)
+	count, _, _, allowed := check(t, s, "client", start, -1)
+	if !allowed || count != 1 {
+		t.Errorf("Expected release to return one unit, got %v/%d", allowed, count)
+	}
+
+	count, _, _, _ = check(t, s, "client", start, -5)
+	if count != 0 {
+		t.Errorf("Expected count not to go below 0, got %d", count)
+	}
+}
+
+func testClientsAreIndependent(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	for i := 0; i <= maxCount; i++ {
+		check(t, s, "a", start, 1)
+	}
+
+	count, _, _, allowed := check(t, s, "b", start, 1)
+	if !allowed || count != 1 {
+		t.Errorf("Expected client b to be unaffected by client a, got %v/%d", allowed, count)
+	}
+}
+
+func testConcurrentAtomicity(t *testing.T, s ratelimiter.StorageV2) {
+	const (
+		goroutines = 20
+		requests   = 10
+		limit      = 50
+	)
+	start := now()
+
+	var (
+		mu     sync.Mutex
+		counts = make(map[int]bool)
+		wg     sync.WaitGroup
+		errs   = make(chan error, goroutines*requests)
+	)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				data, allowed, err := s.CheckAndIncrementN(context.Background(), "client", start, window, limit, block, 1)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !allowed || data.RequestCount > limit {
+					continue
+				}
+
+				mu.Lock()
+				if counts[data.RequestCount] {
+					errs <- fmt.Errorf("count %d observed twice", data.RequestCount)
+				}
+				counts[data.RequestCount] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if len(counts) != limit {
+		t.Errorf("Expected exactly %d requests within the limit, got %d", limit, len(counts))
+	}
+
+	data, _, _ := s.GetClientData(context.Background(), "client")
+	if data == nil || data.RequestCount != limit+1 {
+		t.Errorf("Expected %d counted requests, got %+v", limit+1, data)
+	}
+}
+
+func testDeleteClient(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	for i := 0; i <= maxCount; i++ {
+		check(t, s, "a", start, 1)
+	}
+	check(t, s, "b", start, 1)
+
+	if err := s.DeleteClient(context.Background(), "a"); err != nil {
+		t.Fatalf("DeleteClient: %v", err)
+	}
+	if exists(t, s, "a") {
+		t.Error("Expected client a to be deleted")
+	}
+	if !exists(t, s, "b") {
+		t.Error("Expected client b to remain")
+	}
+
+	count, _, _, allowed := check(t, s, "a", start, 1)
+	if !allowed || count != 1 {
+		t.Errorf("Expected a deleted client to start over unblocked, got %v/%d", allowed, count)
+	}
+
+	if err := s.DeleteClient(context.Background(), "unknown"); err != nil {
+		t.Errorf("Expected deleting an unknown client to succeed, got %v", err)
+	}
+}
+
+func testClear(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	check(t, s, "a", start, 1)
+	check(t, s, "b", start, 1)
+
+	if err := s.Clear(context.Background()); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if exists(t, s, "a") || exists(t, s, "b") {
+		t.Error("Expected Clear to remove every client")
+	}
+}
+
+func testZeroCostIsRead(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	count, _, _, allowed := check(t, s, "client", start, 0)
+	if !allowed || count != 0 {
+		t.Errorf("Expected a zero-cost check of an unknown client to report an empty window, got %v/%d", allowed, count)
+	}
+	if exists(t, s, "client") {
+		t.Error("Expected a zero-cost check not to create the client")
+	}
+
+	check(t, s, "client", start, 2)
+	check(t, s, "client", start, 0)
+	count, _, _, _ = check(t, s, "client", start, 1)
+	if count != 3 {
+		t.Errorf("Expected a zero-cost check not to be counted, got %d", count)
+	}
+}
+
+const (
+	capacity = 3
+	interval = time.Second
+	maxDelay = 2 * time.Second
+)
+
+func takeToken(t *testing.T, s ratelimiter.StorageV2, at time.Time, n int) (float64, bool) {
+	t.Helper()
+
+	bucket, allowed, err := s.TakeToken(context.Background(), "client", at, 1, capacity, n)
+	skipUnsupported(t, err)
+	if err != nil {
+		t.Fatalf("TakeToken: %v", err)
+	}
+	return bucket.Tokens, allowed
+}
+
+func testTokenBucket(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	if tokens, allowed := takeToken(t, s, start, 0); !allowed || tokens != capacity {
+		t.Errorf("Expected a new bucket to be full, got %v/%v", allowed, tokens)
+	}
+
+	if tokens, allowed := takeToken(t, s, start, 2); !allowed || tokens != 1 {
+		t.Errorf("Expected 2 tokens to be taken, got %v/%v", allowed, tokens)
+	}
+	if tokens, allowed := takeToken(t, s, start, 2); allowed || tokens != 1 {
+		t.Errorf("Expected a denied take to leave the bucket unchanged, got %v/%v", allowed, tokens)
+	}
+	if tokens, allowed := takeToken(t, s, start, 0); !allowed || tokens != 1 {
+		t.Errorf("Expected a zero-cost take to report the bucket, got %v/%v", allowed, tokens)
+	}
+
+	if tokens, allowed := takeToken(t, s, start.Add(time.Second), 2); !allowed || tokens != 0 {
+		t.Errorf("Expected one token to be refilled after a second, got %v/%v", allowed, tokens)
+	}
+	if tokens, _ := takeToken(t, s, start.Add(time.Hour), 0); tokens != capacity {
+		t.Errorf("Expected the refill to stop at capacity, got %v", tokens)
+	}
+}
+
+func testTokenBucketRelease(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	takeToken(t, s, start, 3)
+	if tokens, allowed := takeToken(t, s, start, -2); !allowed || tokens != 2 {
+		t.Errorf("Expected release to return 2 tokens, got %v/%v", allowed, tokens)
+	}
+	if tokens, _ := takeToken(t, s, start, -10); tokens != capacity {
+		t.Errorf("Expected release not to overfill the bucket, got %v", tokens)
+	}
+}
+
+func slidingWindow(t *testing.T, s ratelimiter.StorageV2, at time.Time, n int) (int, int, bool) {
+	t.Helper()
+
+	counter, allowed, err := s.SlidingWindowIncrement(context.Background(), "client", at, window, maxCount, n)
+	skipUnsupported(t, err)
+	if err != nil {
+		t.Fatalf("SlidingWindowIncrement: %v", err)
+	}
+	return counter.CurrentCount, counter.PreviousCount, allowed
+}
+
+func testSlidingWindow(t *testing.T, s ratelimiter.StorageV2) {
+	start := now().Truncate(window)
+
+	if current, _, allowed := slidingWindow(t, s, start, 2); !allowed || current != 2 {
+		t.Errorf("Expected 2 requests to be counted, got %v/%d", allowed, current)
+	}
+	if current, _, allowed := slidingWindow(t, s, start, 2); allowed || current != 2 {
+		t.Errorf("Expected a denied request not to be counted, got %v/%d", allowed, current)
+	}
+	if current, _, allowed := slidingWindow(t, s, start, 0); !allowed || current != 2 {
+		t.Errorf("Expected a zero-cost check to report the window, got %v/%d", allowed, current)
+	}
+
+	halfway := start.Add(window + window/2)
+	current, previous, allowed := slidingWindow(t, s, halfway, 2)
+	if !allowed || current != 2 || previous != 2 {
+		t.Errorf("Expected half of the previous window to count, got %v/%d/%d", allowed, current, previous)
+	}
+	if _, _, allowed := slidingWindow(t, s, halfway, 1); allowed {
+		t.Error("Expected the weighted estimate to deny a request over the limit")
+	}
+
+	if current, previous, _ := slidingWindow(t, s, start.Add(3*window), 1); current != 1 || previous != 0 {
+		t.Errorf("Expected counts to reset after an idle window, got %d/%d", current, previous)
+	}
+}
+
+func testSlidingWindowRelease(t *testing.T, s ratelimiter.StorageV2) {
+	start := now().Truncate(window)
+
+	slidingWindow(t, s, start, 3)
+	if current, _, allowed := slidingWindow(t, s, start, -1); !allowed || current != 2 {
+		t.Errorf("Expected release to uncount one request, got %v/%d", allowed, current)
+	}
+	if current, _, _ := slidingWindow(t, s, start, -10); current != 0 {
+		t.Errorf("Expected the count not to go below 0, got %d", current)
+	}
+}
+
+func appendToLog(t *testing.T, s ratelimiter.StorageV2, at time.Time, n int) ([]time.Time, bool) {
+	t.Helper()
+
+	log, allowed, err := s.AppendToLog(context.Background(), "client", at, window, maxCount, n)
+	skipUnsupported(t, err)
+	if err != nil {
+		t.Fatalf("AppendToLog: %v", err)
+	}
+	return log.Timestamps, allowed
+}
+
+func testSlidingLog(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	if log, allowed := appendToLog(t, s, start, 2); !allowed || len(log) != 2 {
+		t.Errorf("Expected 2 entries, got %v/%v", allowed, log)
+	}
+	if log, allowed := appendToLog(t, s, start.Add(time.Second), 2); allowed || len(log) != 2 {
+		t.Errorf("Expected a denied request not to be logged, got %v/%v", allowed, log)
+	}
+
+	log, allowed := appendToLog(t, s, start.Add(time.Second), 1)
+	if !allowed || len(log) != 3 {
+		t.Fatalf("Expected 3 entries, got %v/%v", allowed, log)
+	}
+	if !log[0].Equal(start) || !log[2].Equal(start.Add(time.Second)) {
+		t.Errorf("Expected entries in arrival order, got %v", log)
+	}
+	if log, _ := appendToLog(t, s, start.Add(time.Second), 0); len(log) != 3 {
+		t.Errorf("Expected a zero-cost check to report the log, got %v", log)
+	}
+
+	if log, _ := appendToLog(t, s, start.Add(window), 0); len(log) != 1 || !log[0].Equal(start.Add(time.Second)) {
+		t.Errorf("Expected entries to expire after the window, got %v", log)
+	}
+}
+
+func testSlidingLogRelease(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	appendToLog(t, s, start, 2)
+	appendToLog(t, s, start.Add(time.Second), 1)
+
+	log, allowed := appendToLog(t, s, start.Add(time.Second), -1)
+	if !allowed || len(log) != 2 || !log[1].Equal(start) {
+		t.Errorf("Expected release to remove the most recent entry, got %v/%v", allowed, log)
+	}
+	if log, _ := appendToLog(t, s, start.Add(time.Second), -10); len(log) != 0 {
+		t.Errorf("Expected release to empty the log at most, got %v", log)
+	}
+}
+
+func updateArrivalTime(t *testing.T, s ratelimiter.StorageV2, at time.Time, n int) (time.Time, bool) {
+	t.Helper()
+
+	tat, allowed, err := s.UpdateArrivalTime(context.Background(), "client", at, interval, capacity, n)
+	skipUnsupported(t, err)
+	if err != nil {
+		t.Fatalf("UpdateArrivalTime: %v", err)
+	}
+	return tat, allowed
+}
+
+func testGCRA(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	if tat, allowed := updateArrivalTime(t, s, start, 0); !allowed || !tat.Equal(start) {
+		t.Errorf("Expected a new client to have TAT now, got %v/%v", allowed, tat)
+	}
+
+	if tat, allowed := updateArrivalTime(t, s, start, 2); !allowed || !tat.Equal(start.Add(2*interval)) {
+		t.Errorf("Expected TAT to advance by 2 intervals, got %v/%v", allowed, tat)
+	}
+	if tat, allowed := updateArrivalTime(t, s, start, 2); allowed || !tat.Equal(start.Add(2*interval)) {
+		t.Errorf("Expected a request beyond the burst to be denied without moving TAT, got %v/%v", allowed, tat)
+	}
+	if tat, allowed := updateArrivalTime(t, s, start, 1); !allowed || !tat.Equal(start.Add(3*interval)) {
+		t.Errorf("Expected the rest of the burst to be allowed, got %v/%v", allowed, tat)
+	}
+
+	if tat, allowed := updateArrivalTime(t, s, start.Add(interval), 1); !allowed || !tat.Equal(start.Add(4*interval)) {
+		t.Errorf("Expected one more request after an interval, got %v/%v", allowed, tat)
+	}
+}
+
+func testGCRARelease(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	updateArrivalTime(t, s, start, 3)
+	if tat, allowed := updateArrivalTime(t, s, start, -2); !allowed || !tat.Equal(start.Add(interval)) {
+		t.Errorf("Expected release to move TAT back 2 intervals, got %v/%v", allowed, tat)
+	}
+	if tat, _ := updateArrivalTime(t, s, start, -10); !tat.Equal(start) {
+		t.Errorf("Expected release not to move TAT before now, got %v", tat)
+	}
+}
+
+func scheduleLeak(t *testing.T, s ratelimiter.StorageV2, at time.Time, n int) (time.Time, bool) {
+	t.Helper()
+
+	slot, allowed, err := s.ScheduleLeak(context.Background(), "client", at, interval, maxDelay, n)
+	skipUnsupported(t, err)
+	if err != nil {
+		t.Fatalf("ScheduleLeak: %v", err)
+	}
+	return slot, allowed
+}
+
+func testLeakyBucket(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	if slot, allowed := scheduleLeak(t, s, start, 1); !allowed || !slot.Equal(start) {
+		t.Errorf("Expected the first request to run now, got %v/%v", allowed, slot)
+	}
+	if slot, allowed := scheduleLeak(t, s, start, 2); !allowed || !slot.Equal(start.Add(interval)) {
+		t.Errorf("Expected the next request to wait an interval, got %v/%v", allowed, slot)
+	}
+	if slot, allowed := scheduleLeak(t, s, start, 1); allowed || !slot.Equal(start.Add(3*interval)) {
+		t.Errorf("Expected a request beyond maxDelay to be denied with the queue tail, got %v/%v", allowed, slot)
+	}
+	if slot, allowed := scheduleLeak(t, s, start.Add(interval), 0); !allowed || !slot.Equal(start.Add(3*interval)) {
+		t.Errorf("Expected a zero-cost check to report the queue tail, got %v/%v", allowed, slot)
+	}
+
+	if slot, allowed := scheduleLeak(t, s, start.Add(interval), 1); !allowed || !slot.Equal(start.Add(3*interval)) {
+		t.Errorf("Expected the queue to drain, got %v/%v", allowed, slot)
+	}
+}
+
+func testLeakyBucketRelease(t *testing.T, s ratelimiter.StorageV2) {
+	start := now()
+
+	scheduleLeak(t, s, start, 3)
+	if tail, allowed := scheduleLeak(t, s, start, -1); !allowed || !tail.Equal(start.Add(2*interval)) {
+		t.Errorf("Expected release to give up the last slot, got %v/%v", allowed, tail)
+	}
+	if tail, _ := scheduleLeak(t, s, start, -10); !tail.Equal(start) {
+		t.Errorf("Expected release not to move the tail before now, got %v", tail)
+	}
+}
@@ -0,0 +1,39 @@
+package storagetest_test
+
+import (
+	"testing"
+
+	"github.com/iramosg/devin-ai-ratelimiter/ratelimiter"
+	"github.com/iramosg/devin-ai-ratelimiter/storage"
+	"github.com/iramosg/devin-ai-ratelimiter/storage/storagetest"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storagetest.RunConformance(t, func(t *testing.T) ratelimiter.StorageV2 {
+		return ratelimiter.AdaptStorage(storage.NewMemoryStorage())
+	})
+}
+
+func TestBoundedMemoryStorage(t *testing.T) {
+	storagetest.RunConformance(t, func(t *testing.T) ratelimiter.StorageV2 {
+		return ratelimiter.AdaptStorage(storage.NewBoundedMemoryStorage(100, storage.EvictLeastRecentlyUsed))
+	})
+}
+
+func TestShardedMemoryStorage(t *testing.T) {
+	storagetest.RunConformance(t, func(t *testing.T) ratelimiter.StorageV2 {
+		return ratelimiter.AdaptStorage(storage.NewShardedMemoryStorage(4))
+	})
+}
+
+// plainStorage hides every method of the wrapped storage except those of
+// ratelimiter.Storage, like a backend without the optional interfaces.
+type plainStorage struct {
+	ratelimiter.Storage
+}
+
+func TestPlainStorage(t *testing.T) {
+	storagetest.RunConformance(t, func(t *testing.T) ratelimiter.StorageV2 {
+		return ratelimiter.AdaptStorage(plainStorage{storage.NewMemoryStorage()})
+	})
+}
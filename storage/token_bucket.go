@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"time"
+)
+
+type TokenBucket struct {
+	Tokens     float64
+	LastRefill time.Time
+}
+
+// TakeToken refills the client's bucket and takes n tokens from it if available.
+// A negative n returns tokens to the bucket, up to its capacity. A zero n only
+// reports the bucket: it neither stores it nor counts as a use of the client.
+func (s *MemoryStorage) TakeToken(clientID string, now time.Time, refillRate float64, capacity int, n int) (*TokenBucket, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket := &TokenBucket{
+		Tokens:     float64(capacity),
+		LastRefill: now,
+	}
+	if stored, exists := s.buckets[clientID]; exists {
+		*bucket = *stored
+	}
+
+	if elapsed := now.Sub(bucket.LastRefill); elapsed > 0 {
+		bucket.Tokens += elapsed.Seconds() * refillRate
+		if bucket.Tokens > float64(capacity) {
+			bucket.Tokens = float64(capacity)
+		}
+		bucket.LastRefill = now
+	}
+
+	allowed := bucket.Tokens >= float64(n)
+	if allowed {
+		bucket.Tokens = min(bucket.Tokens-float64(n), float64(capacity))
+	}
+	if n == 0 {
+		return bucket, allowed
+	}
+	s.buckets[clientID] = bucket
+
+	if refillRate > 0 {
+		s.touch(clientID, now.Add(time.Duration((float64(capacity)-bucket.Tokens)/refillRate*float64(time.Second))))
+	}
+
+	s.use(clientID, now, time.Time{})
+
+	bucketCopy := &TokenBucket{
+		Tokens:     bucket.Tokens,
+		LastRefill: bucket.LastRefill,
+	}
+	return bucketCopy, allowed
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeToken_NewClient(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	bucket, allowed := storage.TakeToken("test-client", now, 1, 10, 1)
+	if !allowed {
+		t.Error("Expected first token to be granted")
+	}
+	if bucket.Tokens != 9 {
+		t.Errorf("Expected 9 tokens left, got %v", bucket.Tokens)
+	}
+	if !bucket.LastRefill.Equal(now) {
+		t.Errorf("Expected LastRefill %v, got %v", now, bucket.LastRefill)
+	}
+}
+
+func TestTakeToken_Exhausted(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if _, allowed := storage.TakeToken("test-client", now, 1, 3, 1); !allowed {
+			t.Errorf("Token %d should be granted", i+1)
+		}
+	}
+
+	bucket, allowed := storage.TakeToken("test-client", now, 1, 3, 1)
+	if allowed {
+		t.Error("Expected empty bucket to deny")
+	}
+	if bucket.Tokens != 0 {
+		t.Errorf("Expected 0 tokens, got %v", bucket.Tokens)
+	}
+}
+
+func TestTakeToken_RefillCappedAtCapacity(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	storage.TakeToken("test-client", now, 1, 3, 1)
+	storage.TakeToken("test-client", now, 1, 3, 1)
+
+	bucket, allowed := storage.TakeToken("test-client", now.Add(time.Hour), 1, 3, 1)
+	if !allowed {
+		t.Error("Expected refilled bucket to grant a token")
+	}
+	if bucket.Tokens != 2 {
+		t.Errorf("Expected refill to be capped at capacity, got %v tokens", bucket.Tokens)
+	}
+}
+
+func TestTakeToken_DeleteAndClear(t *testing.T) {
+	storage := NewMemoryStorage()
+	now := time.Now()
+
+	storage.TakeToken("client1", now, 1, 1, 1)
+	storage.TakeToken("client2", now, 1, 1, 1)
+
+	storage.DeleteClient("client1")
+	if _, allowed := storage.TakeToken("client1", now, 1, 1, 1); !allowed {
+		t.Error("Expected deleted client to start with a full bucket")
+	}
+
+	storage.Clear()
+	if _, allowed := storage.TakeToken("client2", now, 1, 1, 1); !allowed {
+		t.Error("Expected cleared client to start with a full bucket")
+	}
+}